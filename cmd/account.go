@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Reasons the API rejects an API key, each with its own exit code
+var (
+	errKeyInvalid = errors.New("the API key is invalid")
+	errKeyExpired = errors.New("the API key has expired")
+	errKeyRevoked = errors.New("the API key has been revoked")
+)
+
+// apiTimeout bounds every request to the Cloud Cents API
+const apiTimeout = 15 * time.Second
+
+// account is what the API reports about the owner of an API key
+type account struct {
+	Account   string    `json:"account" yaml:"account"`
+	Org       string    `json:"org,omitempty" yaml:"org,omitempty"`
+	Plan      string    `json:"plan" yaml:"plan"`
+	ExpiresAt time.Time `json:"expires_at,omitempty" yaml:"expires_at,omitempty"` // zero when the key doesn't expire
+}
+
+// apiError is the body of an error response of the API
+type apiError struct {
+	Error   string `json:"error"` // e.g. invalid_key, key_expired or key_revoked
+	Message string `json:"message"`
+}
+
+// verifyAPIKey asks the API who an API key belongs to. A rejected key gives an
+// error wrapping errKeyInvalid, errKeyExpired or errKeyRevoked.
+func verifyAPIKey(apiKey string) (*account, error) {
+	url := strings.TrimRight(configValue("api_url"), "/") + "/auth/verify"
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+apiKey)
+	req.Header.Set("Accept", "application/json")
+
+	client := &http.Client{Timeout: apiTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("could not reach the Cloud Cents API: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading the response of %s: %v", url, err)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		var a account
+		if err := json.Unmarshal(body, &a); err != nil {
+			return nil, fmt.Errorf("unexpected response from %s: %v", url, err)
+		}
+		if !a.ExpiresAt.IsZero() && a.ExpiresAt.Before(time.Now()) {
+			return nil, fmt.Errorf("%w on %s", errKeyExpired, a.ExpiresAt.Format("2006-01-02"))
+		}
+		return &a, nil
+	case http.StatusUnauthorized, http.StatusForbidden:
+		var e apiError
+		_ = json.Unmarshal(body, &e)
+		reason := errKeyInvalid
+		switch e.Error {
+		case "key_expired":
+			reason = errKeyExpired
+		case "key_revoked":
+			reason = errKeyRevoked
+		}
+		if e.Message != "" {
+			return nil, fmt.Errorf("%w: %s", reason, e.Message)
+		}
+		return nil, reason
+	}
+	return nil, fmt.Errorf("the Cloud Cents API answered %s", resp.Status)
+}
+
+// authExitCode returns the exit code for an error reading or verifying an API key
+func authExitCode(err error) int {
+	switch {
+	case errors.Is(err, errKeyExpired), errors.Is(err, errLoginEnded):
+		return exitKeyExpired
+	case errors.Is(err, errKeyRevoked):
+		return exitKeyRevoked
+	case errors.Is(err, errKeyInvalid), errors.Is(err, errNoAPIKey):
+		return exitAuth
+	}
+	return exitFailure
+}
+
+// authHint tells what to do about a rejected or missing API key
+func authHint(err error) string {
+	switch {
+	case errors.Is(err, errLoginEnded):
+		return "Run 'cloudcents login --device' to log in again."
+	case errors.Is(err, errKeyExpired):
+		return "Renew the key at https://cloud-cents.vercel.app/user, then run 'cloudcents auth'."
+	case errors.Is(err, errKeyRevoked):
+		return "Create a new key at https://cloud-cents.vercel.app/user, then run 'cloudcents auth'."
+	case errors.Is(err, errKeyInvalid):
+		return fmt.Sprintf("Check the key, and that it belongs to %s.", configValue("api_url"))
+	}
+	return ""
+}
+
+// accountView describes an account for the login messages
+func accountView(a *account) string {
+	lines := []string{fmt.Sprintf("Logged in as %s", a.Account)}
+	if a.Org != "" {
+		lines = append(lines, fmt.Sprintf("Organization: %s", a.Org))
+	}
+	if a.Plan != "" {
+		lines = append(lines, fmt.Sprintf("Plan:         %s", a.Plan))
+	}
+	if a.ExpiresAt.IsZero() {
+		lines = append(lines, "Key expires:  never")
+	} else {
+		days := int(time.Until(a.ExpiresAt).Hours() / 24)
+		lines = append(lines, fmt.Sprintf("Key expires:  %s (in %d days)", a.ExpiresAt.Local().Format("2006-01-02"), days))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// maskAPIKey hides all but the last four characters of an API key
+func maskAPIKey(apiKey string) string {
+	if len(apiKey) <= 8 {
+		return strings.Repeat("*", len(apiKey))
+	}
+	return strings.Repeat("*", 8) + apiKey[len(apiKey)-4:]
+}
+
+// keyFingerprint identifies an API key without revealing it, e.g. to tell whether
+// two machines use the same key
+func keyFingerprint(apiKey string) string {
+	sum := sha256.Sum256([]byte(apiKey))
+	return "SHA256:" + hex.EncodeToString(sum[:])[:16]
+}
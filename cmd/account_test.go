@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// verifyServer stands in for the Cloud Cents API, answering /auth/verify by the
+// bearer token it gets
+func verifyServer(t *testing.T, expiresAt time.Time) {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/auth/verify" {
+			http.NotFound(w, r)
+			return
+		}
+		switch r.Header.Get("Authorization") {
+		case "Bearer good":
+			fmt.Fprintf(w, `{"account":"ada@example.com","org":"Analytical Engines","plan":"team","expires_at":%q}`, expiresAt.Format(time.RFC3339))
+		case "Bearer expired":
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error":"key_expired","message":"expired on 2026-01-01"}`)
+		case "Bearer revoked":
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"error":"key_revoked"}`)
+		case "Bearer broken":
+			w.WriteHeader(http.StatusBadGateway)
+			fmt.Fprint(w, "<html>Bad Gateway</html>")
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error":"invalid_key"}`)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	t.Setenv("CLOUDCENTS_API_URL", srv.URL)
+}
+
+func TestVerifyAPIKeyAccount(t *testing.T) {
+	expiresAt := time.Now().Add(45 * 24 * time.Hour).UTC().Truncate(time.Second)
+	verifyServer(t, expiresAt)
+
+	a, err := verifyAPIKey("good")
+	if err != nil {
+		t.Fatalf("verifyAPIKey: %v", err)
+	}
+	if a.Account != "ada@example.com" || a.Org != "Analytical Engines" || a.Plan != "team" {
+		t.Errorf("got account %+v", a)
+	}
+	if !a.ExpiresAt.Equal(expiresAt) {
+		t.Errorf("got expiry %v, want %v", a.ExpiresAt, expiresAt)
+	}
+	view := accountView(a)
+	for _, want := range []string{"Logged in as ada@example.com", "Plan:         team", "Key expires:  " + expiresAt.Local().Format("2006-01-02")} {
+		if !strings.Contains(view, want) {
+			t.Errorf("account view %q lacks %q", view, want)
+		}
+	}
+}
+
+func TestVerifyAPIKeyExpiredAccount(t *testing.T) {
+	verifyServer(t, time.Now().Add(-time.Hour))
+
+	_, err := verifyAPIKey("good")
+	if !errors.Is(err, errKeyExpired) {
+		t.Fatalf("got error %v, want %v", err, errKeyExpired)
+	}
+	if code := authExitCode(err); code != exitKeyExpired {
+		t.Errorf("got exit code %d, want %d", code, exitKeyExpired)
+	}
+}
+
+func TestVerifyAPIKeyRejected(t *testing.T) {
+	verifyServer(t, time.Now().Add(time.Hour))
+
+	tests := []struct {
+		apiKey  string
+		reason  error
+		message string
+		code    int
+	}{
+		{"bogus", errKeyInvalid, "the API key is invalid", exitAuth},
+		{"expired", errKeyExpired, "the API key has expired: expired on 2026-01-01", exitKeyExpired},
+		{"revoked", errKeyRevoked, "the API key has been revoked", exitKeyRevoked},
+		{"broken", nil, "the Cloud Cents API answered 502 Bad Gateway", exitFailure},
+	}
+	for _, tt := range tests {
+		t.Run(tt.apiKey, func(t *testing.T) {
+			a, err := verifyAPIKey(tt.apiKey)
+			if err == nil {
+				t.Fatalf("got account %+v, want an error", a)
+			}
+			if tt.reason != nil && !errors.Is(err, tt.reason) {
+				t.Errorf("got error %v, want %v", err, tt.reason)
+			}
+			for _, reason := range []error{errKeyInvalid, errKeyExpired, errKeyRevoked} {
+				if reason != tt.reason && errors.Is(err, reason) {
+					t.Errorf("error %v also matches %v", err, reason)
+				}
+			}
+			if err.Error() != tt.message {
+				t.Errorf("got message %q, want %q", err.Error(), tt.message)
+			}
+			if code := authExitCode(err); code != tt.code {
+				t.Errorf("got exit code %d, want %d", code, tt.code)
+			}
+		})
+	}
+}
+
+func TestAuthExitCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		code int
+	}{
+		{errNoAPIKey, exitAuth},
+		{fmt.Errorf("logging in: %w", errKeyInvalid), exitAuth},
+		{fmt.Errorf("logging in: %w", errKeyExpired), exitKeyExpired},
+		{errLoginEnded, exitKeyExpired},
+		{fmt.Errorf("logging in: %w", errKeyRevoked), exitKeyRevoked},
+		{errors.New("could not reach the Cloud Cents API"), exitFailure},
+	}
+	for _, tt := range tests {
+		if code := authExitCode(tt.err); code != tt.code {
+			t.Errorf("authExitCode(%v) = %d, want %d", tt.err, code, tt.code)
+		}
+	}
+}
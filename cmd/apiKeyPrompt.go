@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+var promptHelpStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("241"))
+
+// apiKeyPrompt asks for an API key without echoing it, so it stays out of the
+// terminal's scrollback as well as the shell history
+type apiKeyPrompt struct {
+	value     []rune
+	done      bool
+	cancelled bool
+}
+
+func (m apiKeyPrompt) Init() tea.Cmd {
+	return nil
+}
+
+func (m apiKeyPrompt) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+	switch key.Type {
+	case tea.KeyEnter:
+		if strings.TrimSpace(string(m.value)) != "" {
+			m.done = true
+			return m, tea.Quit
+		}
+	case tea.KeyEsc, tea.KeyCtrlC:
+		m.cancelled = true
+		return m, tea.Quit
+	case tea.KeyBackspace:
+		if len(m.value) > 0 {
+			m.value = m.value[:len(m.value)-1]
+		}
+	case tea.KeyCtrlU:
+		m.value = nil
+	case tea.KeyRunes:
+		m.value = append(m.value, key.Runes...)
+	}
+	return m, nil
+}
+
+func (m apiKeyPrompt) View() string {
+	if m.done || m.cancelled {
+		return ""
+	}
+	typed := "nothing typed yet"
+	if len(m.value) > 0 {
+		typed = fmt.Sprintf("%d characters", len(m.value))
+	}
+	return fmt.Sprintf("API key for profile %s (input is hidden): ▏ %s\n%s\n",
+		currentProfile, promptHelpStyle.Render("("+typed+")"),
+		promptHelpStyle.Render("Enter to save, Ctrl+U to clear, Esc to cancel"))
+}
+
+// promptAPIKey asks for an API key on the terminal
+func promptAPIKey() (string, error) {
+	final, err := tea.NewProgram(apiKeyPrompt{}, tea.WithOutput(os.Stderr)).Run()
+	if err != nil {
+		return "", fmt.Errorf("reading the API key: %v", err)
+	}
+	m := final.(apiKeyPrompt)
+	if m.cancelled {
+		return "", fmt.Errorf("cancelled, no API key stored")
+	}
+	return string(m.value), nil
+}
@@ -0,0 +1,21 @@
+package cmd
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestAPIKeyPromptIgnoresSpace(t *testing.T) {
+	var m tea.Model = apiKeyPrompt{}
+	for _, msg := range []tea.KeyMsg{
+		{Type: tea.KeyRunes, Runes: []rune("cc_")},
+		{Type: tea.KeySpace, Runes: []rune(" ")},
+		{Type: tea.KeyRunes, Runes: []rune("live")},
+	} {
+		m, _ = m.Update(msg)
+	}
+	if got := string(m.(apiKeyPrompt).value); got != "cc_live" {
+		t.Errorf("got API key %q, want %q", got, "cc_live")
+	}
+}
@@ -1,23 +1,18 @@
 package cmd
 
 import (
-	"crypto/aes"
-	"crypto/cipher"
-	"crypto/rand"
-	"encoding/base64"
+	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 
 	"github.com/charmbracelet/lipgloss"
 	"github.com/spf13/cobra"
 )
 
-var encryptionKey = []byte("myverystrongpasswordo32bitlength")
-
 // Styling with lipgloss
 var borderStyle = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).Padding(1, 2).Bold(true)
 var successStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("46")).Bold(true) // Green for success
@@ -28,124 +23,197 @@ var infoStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("75")).Italic(true
 var authCmd = &cobra.Command{
 	Use:   "auth [api_key]",
 	Short: "Store an API key securely and log in",
-	Args:  cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
-		apiKey := args[0]
-		storeAPIKey(apiKey)
+	Long: `Store an API key securely and log in. Without an argument the key is asked for
+on the terminal without echoing it; --with-token reads it from stdin and
+--from-file from a file. A key given as an argument works too, but ends up in
+your shell history and in the process list.
+
+With --profile the key is stored in that profile, which is created if needed,
+and --api-url sets the API the profile's key belongs to.`,
+	Example: `  cloudcents auth
+  cloudcents auth --with-token < key.txt
+  op read op://dev/cloudcents/key | cloudcents auth --with-token
+  cloudcents auth --from-file ~/secrets/cloudcents.key`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		apiKey, err := apiKeyFromInput(cmd, args)
+		if err != nil {
+			return err
+		}
+		if err := storeAPIKey(apiKey); err != nil {
+			return err
+		}
+		if apiURL, _ := cmd.Flags().GetString("api-url"); apiURL != "" {
+			config, err := readConfigMap()
+			if err != nil {
+				return err
+			}
+			setConfigValue(config, []string{"profiles", currentProfile, "api_url"}, apiURL)
+			if err := writeConfigFile(config); err != nil {
+				return fmt.Errorf("writing config file '%s': %v", configPath(), err)
+			}
+		}
 
 		// Automatically trigger login after storing API key
-		err := loginWithStoredAPIKey()
+		a, err := loginWithStoredAPIKey()
 		if err != nil {
-			displayError(fmt.Sprintf("Error during login: %v", err))
-			os.Exit(1)
-		} else {
-			displaySuccess("Successfully logged in with stored API key.")
+			displayError(fmt.Sprintf("Error: logging in: %v", err))
+			if hint := authHint(err); hint != "" {
+				fmt.Fprintln(os.Stderr, infoStyle.Render(hint))
+			}
+			return withExitCode(authExitCode(err), nil)
 		}
+		displaySuccess(accountView(a))
+		return nil
 	},
 }
 
-// storeAPIKey encrypts and saves the API key to a file in the config directory
-func storeAPIKey(apiKey string) {
-	configDir := getConfigDir()
-	filePath := filepath.Join(configDir, "api_key.txt")
+// apiKeyFromInput returns the API key given to auth: as an argument, on stdin with
+// --with-token, in a file with --from-file or, without any of them, typed at a prompt
+func apiKeyFromInput(cmd *cobra.Command, args []string) (string, error) {
+	withToken, _ := cmd.Flags().GetBool("with-token")
+	fromFile, _ := cmd.Flags().GetString("from-file")
+	given := 0
+	for _, g := range []bool{len(args) > 0, withToken, fromFile != ""} {
+		if g {
+			given++
+		}
+	}
+	if given > 1 {
+		return "", withExitCode(exitUsage, fmt.Errorf("give the API key either as an argument, with --with-token or with --from-file"))
+	}
 
-	// Create the config folder if it doesn't exist
-	if _, err := os.Stat(configDir); os.IsNotExist(err) {
-		err := os.MkdirAll(configDir, 0755)
+	var data []byte
+	var err error
+	switch {
+	case len(args) > 0:
+		fmt.Fprintln(os.Stderr, infoStyle.Render("Warning: an API key given as an argument is saved in your shell history and visible to other users in the process list. Use 'cloudcents auth' without it, --with-token or --from-file instead."))
+		data = []byte(args[0])
+	case withToken:
+		if isTerminal(os.Stdin) {
+			fmt.Fprintln(os.Stderr, infoStyle.Render("Reading the API key from stdin, end with Ctrl+D."))
+		}
+		data, err = io.ReadAll(os.Stdin)
+		if err != nil {
+			return "", fmt.Errorf("reading the API key from stdin: %v", err)
+		}
+	case fromFile != "":
+		data, err = os.ReadFile(fromFile)
 		if err != nil {
-			displayError(fmt.Sprintf("Error creating config folder: %v", err))
-			os.Exit(1)
+			return "", fmt.Errorf("reading the API key: %v", err)
+		}
+	default:
+		if !isTerminal(os.Stdin) {
+			return "", withExitCode(exitUsage, fmt.Errorf("no API key given: pipe it in with --with-token, or use --from-file"))
 		}
+		apiKey, err := promptAPIKey()
+		if err != nil {
+			return "", err
+		}
+		data = []byte(apiKey)
 	}
 
-	// Encrypt the API key before storing it
-	encryptedKey, err := encrypt(apiKey)
-	if err != nil {
-		displayError(fmt.Sprintf("Error encrypting API key: %v", err))
-		os.Exit(1)
+	apiKey := strings.TrimSpace(string(data))
+	if apiKey == "" {
+		return "", withExitCode(exitUsage, fmt.Errorf("the API key is empty"))
 	}
-
-	// Store the encrypted API key in the file
-	err = ioutil.WriteFile(filePath, []byte(encryptedKey), 0600)
-	if err != nil {
-		displayError(fmt.Sprintf("Error storing API key: %v", err))
-		os.Exit(1)
+	if strings.ContainsAny(apiKey, " \t\r\n") {
+		return "", withExitCode(exitUsage, fmt.Errorf("the API key contains whitespace, expected a single key"))
 	}
+	return apiKey, nil
+}
 
-	displayInfo(fmt.Sprintf("API key stored securely in '%s'", filePath))
+// storeAPIKey saves the API key of the active profile in the secret store
+func storeAPIKey(apiKey string) error {
+	return storeSecret(apiKey, "API key")
 }
 
-// encrypt encrypts the API key using AES
-func encrypt(plaintext string) (string, error) {
-	block, err := aes.NewCipher(encryptionKey)
+// storeSecret saves the credentials of the active profile in the secret store: an
+// API key, or the tokens of a device login
+func storeSecret(secret, kind string) error {
+	store, err := secrets()
 	if err != nil {
-		return "", err
+		return err
 	}
-
-	gcm, err := cipher.NewGCM(block)
-	if err != nil {
-		return "", err
+	// The profile's folder lists it in profile list, wherever its key is stored
+	if err := os.MkdirAll(filepath.Dir(apiKeyPath(currentProfile)), 0700); err != nil {
+		return fmt.Errorf("creating config folder: %v", err)
 	}
-
-	nonce := make([]byte, gcm.NonceSize())
-	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
-		return "", err
+	if err := store.set(currentProfile, secret); err != nil {
+		return fmt.Errorf("storing %s in the %s: %v", kind, store.name(), err)
 	}
 
-	ciphertext := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
-	return base64.StdEncoding.EncodeToString(ciphertext), nil
-}
-
-// loginWithStoredAPIKey reads the stored API key and logs in
-func loginWithStoredAPIKey() error {
-	_, err := readAPIKey()
-	if err != nil {
-		return err
+	// Don't leave an older key file behind, e.g. one in the legacy format
+	message := fmt.Sprintf("%s of profile %s stored in '%s', encrypted with your passphrase", kind, currentProfile, apiKeyPath(currentProfile))
+	if _, ok := store.(fileStore); !ok {
+		if err := (fileStore{}).delete(currentProfile); err != nil {
+			return fmt.Errorf("removing old API key file: %v", err)
+		}
+		message = fmt.Sprintf("%s of profile %s stored securely in the %s", kind, currentProfile, store.name())
+	}
+	if kind == "API key" {
+		displayInfo(message)
+	} else {
+		fmt.Println(infoStyle.Render(message))
 	}
-
 	return nil
 }
 
-// readAPIKey reads and decrypts the stored API key
-func readAPIKey() (string, error) {
-	filePath := filepath.Join(getConfigDir(), "api_key.txt")
-	encryptedKey, err := ioutil.ReadFile(filePath)
-	if err != nil {
-		return "", fmt.Errorf("could not read API key: %v", err)
+// deleteAPIKey removes the API key of a profile from every secret store and
+// reports whether there was one
+func deleteAPIKey(profile string) (bool, error) {
+	found := fileStore{}.has(profile)
+	if err := (fileStore{}).delete(profile); err != nil {
+		return found, fmt.Errorf("deleting '%s': %v", apiKeyPath(profile), err)
 	}
-
-	return decrypt(string(encryptedKey))
+	if keyringAvailable() {
+		if (keyringStore{}).has(profile) {
+			found = true
+		}
+		if err := (keyringStore{}).delete(profile); err != nil {
+			return found, fmt.Errorf("deleting the API key from the %s: %v", keyringStore{}.name(), err)
+		}
+	}
+	return found, nil
 }
 
-// decrypt decrypts the stored API key using AES
-func decrypt(ciphertext string) (string, error) {
-	block, err := aes.NewCipher(encryptionKey)
+// loginWithStoredAPIKey reads the API key of the active profile and verifies it
+// against the API
+func loginWithStoredAPIKey() (*account, error) {
+	apiKey, err := readAPIKey()
 	if err != nil {
-		return "", err
+		return nil, err
 	}
+	return verifyAPIKey(apiKey)
+}
 
-	gcm, err := cipher.NewGCM(block)
-	if err != nil {
+// readAPIKey returns the API key of the active profile: CLOUDCENTS_API_KEY, for CI,
+// or the key in the secret store. A key file in the legacy format is migrated first.
+// After a device login it is the access token, refreshed when it is about to expire.
+func readAPIKey() (string, error) {
+	if apiKey := os.Getenv("CLOUDCENTS_API_KEY"); apiKey != "" {
+		return apiKey, nil
+	}
+	if err := migrateLegacyKey(currentProfile, true); err != nil {
 		return "", err
 	}
-
-	encryptedData, err := base64.StdEncoding.DecodeString(ciphertext)
+	store, err := secrets()
 	if err != nil {
 		return "", err
 	}
-
-	nonceSize := gcm.NonceSize()
-	if len(encryptedData) < nonceSize {
-		return "", fmt.Errorf("ciphertext too short")
+	secret, err := store.get(currentProfile)
+	if errors.Is(err, errNoAPIKey) && (fileStore{}).has(currentProfile) {
+		// Stored while secret_store was file
+		store = fileStore{}
+		secret, err = store.get(currentProfile)
 	}
-
-	nonce, ciphertextBytes := encryptedData[:nonceSize], encryptedData[nonceSize:]
-	plaintext, err := gcm.Open(nil, nonce, ciphertextBytes, nil)
 	if err != nil {
 		return "", err
 	}
-
-	return string(plaintext), nil
+	if tokens, ok := parseStoredTokens(secret); ok {
+		return accessToken(store, tokens)
+	}
+	return secret, nil
 }
 
 // getConfigDir determines the OS-specific configuration directory
@@ -163,9 +231,9 @@ func displaySuccess(message string) {
 	fmt.Println(borderStyle.Render(successStyle.Render(message)))
 }
 
-// displayError formats and displays an error message
+// displayError formats and displays an error message on stderr
 func displayError(message string) {
-	fmt.Println(borderStyle.Render(errorStyle.Render(message)))
+	fmt.Fprintln(os.Stderr, borderStyle.Render(errorStyle.Render(message)))
 }
 
 // displayInfo formats and displays an informational message
@@ -175,5 +243,8 @@ func displayInfo(message string) {
 }
 
 func init() {
+	authCmd.Flags().Bool("with-token", false, "Read the API key from stdin")
+	authCmd.Flags().String("from-file", "", "Read the API key from a file")
+	authCmd.Flags().String("api-url", "", "Base URL of the Cloud Cents API the key belongs to, saved in the profile")
 	rootCmd.AddCommand(authCmd)
 }
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// authStatus is where the credentials of a profile come from and whether they work
+type authStatus struct {
+	Profile  string `json:"profile" yaml:"profile"`
+	APIURL   string `json:"api_url" yaml:"api_url"`
+	Source   string `json:"source" yaml:"source"` // empty when there are no credentials
+	Verified bool   `json:"verified" yaml:"verified"`
+	Account  string `json:"account,omitempty" yaml:"account,omitempty"`
+	Error    string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// authStatusCmd shows where the credentials come from and verifies them
+var authStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show where the API key comes from and whether the API accepts it",
+	Long: `Show where the API key of the active profile comes from (CLOUDCENTS_API_KEY,
+the OS keyring or the passphrase-encrypted file) and verify it against the API.
+
+Exits with the same codes as 'cloudcents login' when there is no key or the API
+rejects it, so scripts can check whether they are logged in.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := getOutputFormat(cmd)
+		if err != nil {
+			return err
+		}
+
+		status := authStatus{Profile: currentProfile, APIURL: configValue("api_url"), Source: credentialSource(currentProfile)}
+		var a *account
+		var loginErr error
+		if status.Source == "" {
+			loginErr = errNoAPIKey
+		} else {
+			a, loginErr = loginWithStoredAPIKey()
+		}
+		if loginErr != nil {
+			status.Error = loginErr.Error()
+		} else {
+			status.Verified, status.Account = true, a.Account
+		}
+
+		header := []string{"profile", "api_url", "source", "verified", "account", "error"}
+		rows := [][]string{{status.Profile, status.APIURL, status.Source, fmt.Sprint(status.Verified), status.Account, status.Error}}
+		switch format {
+		case "json":
+			err = writeJSON(os.Stdout, status)
+		case "yaml":
+			err = writeYAML(os.Stdout, status)
+		case "csv":
+			err = writeCSV(os.Stdout, header, rows)
+		case "markdown":
+			err = writeMarkdownTable(os.Stdout, header, rows)
+		default:
+			source := status.Source
+			if source == "" {
+				source = "none"
+			}
+			fmt.Printf("Profile:  %s\n", status.Profile)
+			fmt.Printf("API URL:  %s\n", status.APIURL)
+			fmt.Printf("API key:  %s\n", source)
+			if status.Verified {
+				fmt.Printf("Verified: %s\n", successStyle.Render("yes, as "+status.Account))
+			} else {
+				fmt.Printf("Verified: %s\n", errorStyle.Render("no, "+status.Error))
+			}
+		}
+		if err != nil {
+			return err
+		}
+		if loginErr != nil {
+			return withExitCode(authExitCode(loginErr), nil)
+		}
+		return nil
+	},
+}
+
+func init() {
+	authCmd.AddCommand(authStatusCmd)
+}
@@ -0,0 +1,422 @@
+package cmd
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// defaultCatalog is the versioned catalog built into the binary, used when no other catalog is found
+//
+//go:embed data/catalog.json
+var defaultCatalog []byte
+
+// catalogSource describes where the pricing catalog was loaded from
+var catalogSource string
+
+// catalogWarnings are the validation warnings of the loaded pricing catalog
+var catalogWarnings []catalogIssue
+
+// Catalog is a provider-agnostic pricing catalog. Each provider offers a set of
+// services, and every service is priced through a list of SKUs.
+type Catalog struct {
+	Schema    string     `json:"$schema,omitempty"`
+	Version   string     `json:"version,omitempty"`
+	Providers []Provider `json:"providers"`
+}
+
+// Provider is a cloud provider or private rate card in the catalog
+type Provider struct {
+	Name     string    `json:"name"`
+	Label    string    `json:"label,omitempty"`
+	Services []Service `json:"services"`
+}
+
+// Service groups the SKUs of one kind of resource, e.g. compute or storage
+type Service struct {
+	Name string `json:"name"`
+	SKUs []SKU  `json:"skus"`
+}
+
+// SKU is a single priced item; Size is the key used to compare SKUs across providers.
+// SKUs without a Region apply to every region, and SKUs without a Model are on-demand.
+type SKU struct {
+	ID         string            `json:"id,omitempty"`
+	Size       string            `json:"size"`
+	Region     string            `json:"region,omitempty"`
+	Model      string            `json:"model,omitempty"`
+	Unit       string            `json:"unit,omitempty"`
+	Price      float64           `json:"price"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// DisplayName returns the label shown in table headers for the provider
+func (p Provider) DisplayName() string {
+	if p.Label != "" {
+		return p.Label
+	}
+	return strings.ToUpper(p.Name)
+}
+
+// service returns the named service of the provider, or nil if it is not offered
+func (p *Provider) service(name string) *Service {
+	for i := range p.Services {
+		if p.Services[i].Name == name {
+			return &p.Services[i]
+		}
+	}
+	return nil
+}
+
+// provider returns the named provider, or nil if it is not in the catalog
+func (c *Catalog) provider(name string) *Provider {
+	for i := range c.Providers {
+		if c.Providers[i].Name == name {
+			return &c.Providers[i]
+		}
+	}
+	return nil
+}
+
+// serviceNames returns every service offered by any provider, in catalog order
+func (c *Catalog) serviceNames() []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, p := range c.Providers {
+		for _, s := range p.Services {
+			if !seen[s.Name] {
+				seen[s.Name] = true
+				names = append(names, s.Name)
+			}
+		}
+	}
+	return names
+}
+
+// sizeNames returns every size of a service across all providers, in catalog order
+func (c *Catalog) sizeNames(service string) []string {
+	var names []string
+	seen := map[string]bool{}
+	for i := range c.Providers {
+		s := c.Providers[i].service(service)
+		if s == nil {
+			continue
+		}
+		for _, sku := range s.SKUs {
+			if !seen[sku.Size] {
+				seen[sku.Size] = true
+				names = append(names, sku.Size)
+			}
+		}
+	}
+	return names
+}
+
+// geographies returns the geographies a service size is priced in, in catalog
+// order. It returns a single empty geography when no SKU is region-specific.
+func (c *Catalog) geographies(service, size string) []string {
+	var geos []string
+	seen := map[string]bool{}
+	for i := range c.Providers {
+		s := c.Providers[i].service(service)
+		if s == nil {
+			continue
+		}
+		for _, sku := range s.SKUs {
+			if sku.Size != size || sku.Region == "" {
+				continue
+			}
+			geo := regionGeography(sku.Region)
+			if !seen[geo] {
+				seen[geo] = true
+				geos = append(geos, geo)
+			}
+		}
+	}
+	if len(geos) == 0 {
+		return []string{""}
+	}
+	return geos
+}
+
+// regions returns every region a SKU of the catalog is priced in, in catalog order
+func (c *Catalog) regions() []string {
+	var regions []string
+	seen := map[string]bool{}
+	for _, p := range c.Providers {
+		for _, s := range p.Services {
+			for _, sku := range s.SKUs {
+				if sku.Region != "" && !seen[sku.Region] {
+					seen[sku.Region] = true
+					regions = append(regions, sku.Region)
+				}
+			}
+		}
+	}
+	return regions
+}
+
+// lookup returns the lowest price a provider charges for a service size within a
+// geography, normalized to the selected period. The boolean is false when the
+// provider has no matching SKU.
+func (c *Catalog) lookup(provider, service, size, geo string) (float64, bool) {
+	sku, ok := c.lookupSKU(provider, service, size, geo)
+	if !ok {
+		return 0, false
+	}
+	return normalizePrice(sku.Price, sku.Unit), true
+}
+
+// lookupSKU returns the cheapest SKU of a provider for a service size within a
+// geography in the selected pricing model
+func (c *Catalog) lookupSKU(provider, service, size, geo string) (SKU, bool) {
+	return c.lookupModelSKU(provider, service, size, geo, pricingModel)
+}
+
+// lookupModelSKU returns the cheapest SKU of a provider for a service size within a
+// geography in a pricing model or model family; an empty geography matches every
+// region. SKUs priced in the geography win over region-less SKUs.
+func (c *Catalog) lookupModelSKU(provider, service, size, geo, model string) (SKU, bool) {
+	p := c.provider(provider)
+	if p == nil {
+		return SKU{}, false
+	}
+	s := p.service(service)
+	if s == nil {
+		return SKU{}, false
+	}
+
+	var regional, global SKU
+	foundRegional, foundGlobal := false, false
+	cheaper := func(a, b SKU) bool {
+		return normalizePrice(a.Price, a.Unit) < normalizePrice(b.Price, b.Unit)
+	}
+	for _, sku := range s.SKUs {
+		if sku.Size != size || !modelMatches(sku.Model, model) {
+			continue
+		}
+		switch {
+		case sku.Region == "":
+			if !foundGlobal || cheaper(sku, global) {
+				global = sku
+				foundGlobal = true
+			}
+		case geo == "" || regionGeography(sku.Region) == geo:
+			if !foundRegional || cheaper(sku, regional) {
+				regional = sku
+				foundRegional = true
+			}
+		}
+	}
+	if foundRegional && (geo != "" || !foundGlobal || cheaper(regional, global)) {
+		return regional, true
+	}
+	return global, foundGlobal
+}
+
+// skuByID returns the on-demand SKU of a provider's service with the given ID in a
+// geography, e.g. the m5.large compute SKU of AWS in us-east
+func (c *Catalog) skuByID(provider, service, id, geo string) (SKU, bool) {
+	p := c.provider(provider)
+	if p == nil {
+		return SKU{}, false
+	}
+	s := p.service(service)
+	if s == nil {
+		return SKU{}, false
+	}
+	for _, sku := range s.SKUs {
+		if sku.ID == id && sku.Model == "" && (geo == "" || sku.Region == "" || regionGeography(sku.Region) == geo) {
+			return sku, true
+		}
+	}
+	return SKU{}, false
+}
+
+// equivalentSKU returns the cheapest on-demand compute SKU of a provider in a
+// geography with at least the given vCPUs and GiB of memory, judged by the SKUs'
+// "vcpu" and "memory_gib" attributes
+func (c *Catalog) equivalentSKU(provider, geo string, vcpu, memory float64) (SKU, bool) {
+	p := c.provider(provider)
+	if p == nil {
+		return SKU{}, false
+	}
+	s := p.service("compute")
+	if s == nil {
+		return SKU{}, false
+	}
+
+	var best SKU
+	found := false
+	for _, sku := range s.SKUs {
+		if sku.Model != "" || (geo != "" && sku.Region != "" && regionGeography(sku.Region) != geo) {
+			continue
+		}
+		v, err1 := strconv.ParseFloat(sku.Attributes["vcpu"], 64)
+		m, err2 := strconv.ParseFloat(sku.Attributes["memory_gib"], 64)
+		if err1 != nil || err2 != nil || v < vcpu || m < memory {
+			continue
+		}
+		if !found || normalizePrice(sku.Price, sku.Unit) < normalizePrice(best.Price, best.Unit) {
+			best = sku
+			found = true
+		}
+	}
+	return best, found
+}
+
+// unit returns the catalog unit a service size is priced in, taken from its first SKU
+func (c *Catalog) unit(service, size string) string {
+	for i := range c.Providers {
+		s := c.Providers[i].service(service)
+		if s == nil {
+			continue
+		}
+		for _, sku := range s.SKUs {
+			if sku.Size == size {
+				return sku.Unit
+			}
+		}
+	}
+	return ""
+}
+
+// merge adds the SKUs of an imported provider to the catalog. An imported SKU
+// replaces the one the provider already had with the same identity (see skuKey),
+// so re-importing a file updates prices and other SKUs of the region are kept.
+func (c *Catalog) merge(imported Provider) {
+	p := c.provider(imported.Name)
+	if p == nil {
+		c.Providers = append(c.Providers, Provider{Name: imported.Name, Label: imported.Label})
+		p = &c.Providers[len(c.Providers)-1]
+	}
+	if imported.Label != "" {
+		p.Label = imported.Label
+	}
+
+	for _, is := range imported.Services {
+		s := p.service(is.Name)
+		if s == nil {
+			p.Services = append(p.Services, Service{Name: is.Name})
+			s = &p.Services[len(p.Services)-1]
+		}
+
+		replaced := map[string]bool{}
+		for _, sku := range is.SKUs {
+			replaced[skuKey(sku)] = true
+		}
+		kept := s.SKUs[:0]
+		for _, sku := range s.SKUs {
+			if !replaced[skuKey(sku)] {
+				kept = append(kept, sku)
+			}
+		}
+		s.SKUs = append(kept, is.SKUs...)
+	}
+}
+
+// skuKey identifies a SKU within a service: its ID, or its size when it has none,
+// in its region and pricing model. IDs repeat across regions and pricing models.
+func skuKey(sku SKU) string {
+	id := "id:" + sku.ID
+	if sku.ID == "" {
+		id = "size:" + sku.Size
+	}
+	return strings.Join([]string{id, sku.Region, sku.Model}, "|")
+}
+
+// loadPricingData loads the pricing catalog into prices. A catalog given with the
+// --catalog flag (or its CLOUDCENTS_CATALOG and config file defaults) is used as it
+// is. Otherwise the local catalog written by imports is layered over the catalog
+// built into the binary, so importing one provider doesn't hide the others.
+// A catalog that can't be read or parsed is an error. The exchange rate of
+// --currency and the heatmap settings are loaded with it.
+func loadPricingData(cmd *cobra.Command) error {
+	if err := setCurrency(cmd); err != nil {
+		return err
+	}
+	if err := setHeatmap(cmd); err != nil {
+		return err
+	}
+
+	if path, _ := cmd.Flags().GetString("catalog"); path != "" {
+		c, warnings, err := readCatalogFile(path)
+		if err != nil {
+			return fmt.Errorf("could not load pricing catalog '%s': %v", path, err)
+		}
+		prices, catalogWarnings = c, warnings
+		catalogSource = path
+		if c.Version != "" {
+			catalogSource += ", version " + c.Version
+		}
+		return nil
+	}
+
+	c, warnings, err := parseCatalog(defaultCatalog)
+	if err != nil {
+		return fmt.Errorf("built-in pricing catalog is invalid: %v", err)
+	}
+	catalogSource = "built-in catalog, version " + c.Version
+
+	path := localCatalogPath()
+	if _, err := os.Stat(path); err == nil {
+		local, localWarnings, err := readCatalogFile(path)
+		if err != nil {
+			return fmt.Errorf("could not load pricing catalog '%s': %v", path, err)
+		}
+		// Imported regions replace the built-in ones of the same provider and service
+		for _, p := range local.Providers {
+			c.merge(p)
+		}
+		warnings = append(warnings, localWarnings...)
+		catalogSource = path + " over the " + catalogSource
+	}
+	prices, catalogWarnings = c, warnings
+	return nil
+}
+
+// parseCatalog parses and validates a JSON pricing catalog. It returns the
+// validation warnings, or an error if the catalog has validation errors.
+func parseCatalog(data []byte) (Catalog, []catalogIssue, error) {
+	c, issues := validateCatalog(data)
+	var warnings []catalogIssue
+	for _, i := range issues {
+		if i.Severity == "error" {
+			return c, nil, catalogError{issues}
+		}
+		warnings = append(warnings, i)
+	}
+	return c, warnings, nil
+}
+
+// localCatalogPath returns the path of the catalog that imports are written to
+func localCatalogPath() string {
+	return filepath.Join(getConfigDir(), "catalog.json")
+}
+
+// readCatalogFile reads and validates a pricing catalog from a JSON file
+func readCatalogFile(path string) (Catalog, []catalogIssue, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Catalog{}, nil, err
+	}
+	return parseCatalog(data)
+}
+
+// writeCatalogFile writes a pricing catalog to a JSON file, creating its folder if needed
+func writeCatalogFile(path string, c Catalog) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
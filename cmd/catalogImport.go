@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// catalogCmd groups the commands that manage the local pricing catalog
+var catalogCmd = &cobra.Command{
+	Use:   "catalog",
+	Short: "Manage the local pricing catalog used by prices",
+}
+
+// catalogImportCmd groups the importers for provider price list files
+var catalogImportCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Import a provider's price list file into the local catalog",
+}
+
+// importCatalog merges an imported provider into the local catalog, adds its prices
+// to the price history and reports what was imported
+func importCatalog(source string, imported Provider) error {
+	path := localCatalogPath()
+
+	// Start from the existing local catalog so other providers and regions are kept
+	var c Catalog
+	if _, err := os.Stat(path); err == nil {
+		c, _, err = readCatalogFile(path)
+		if err != nil {
+			return fmt.Errorf("reading local catalog '%s': %v", path, err)
+		}
+	}
+
+	c.merge(imported)
+	if err := writeCatalogFile(path, c); err != nil {
+		return fmt.Errorf("writing local catalog '%s': %v", path, err)
+	}
+	if err := recordPriceSnapshot(source, imported); err != nil {
+		displayError(fmt.Sprintf("The prices were imported, but could not be added to the price history: %v", err))
+	}
+
+	total := 0
+	var counts []string
+	for _, s := range imported.Services {
+		total += len(s.SKUs)
+		counts = append(counts, fmt.Sprintf("%s: %d", s.Name, len(s.SKUs)))
+	}
+	sort.Strings(counts)
+	displaySuccess(fmt.Sprintf("Imported %d %s SKUs from '%s' into '%s'\n%s",
+		total, imported.DisplayName(), source, path, strings.Join(counts, ", ")))
+	return nil
+}
+
+// computeSize returns the comparable size of a compute SKU, e.g. "2vcpu-8gb"
+func computeSize(vcpu, memoryGiB float64) string {
+	return fmt.Sprintf("%svcpu-%sgb", formatQuantity(vcpu), formatQuantity(memoryGiB))
+}
+
+// sortSKUs orders imported SKUs by vCPU and memory, then by ID, region and pricing
+// model, so the catalog and the table rows are stable between imports
+func sortSKUs(skus []SKU) {
+	attr := func(sku SKU, name string) float64 {
+		v, _ := strconv.ParseFloat(sku.Attributes[name], 64)
+		return v
+	}
+	sort.SliceStable(skus, func(i, j int) bool {
+		a, b := skus[i], skus[j]
+		if attr(a, "vcpu") != attr(b, "vcpu") {
+			return attr(a, "vcpu") < attr(b, "vcpu")
+		}
+		if attr(a, "memory_gib") != attr(b, "memory_gib") {
+			return attr(a, "memory_gib") < attr(b, "memory_gib")
+		}
+		if a.ID != b.ID {
+			return a.ID < b.ID
+		}
+		if a.Region != b.Region {
+			return a.Region < b.Region
+		}
+		return a.Model < b.Model
+	})
+}
+
+// formatQuantity formats a number without trailing zeros
+func formatQuantity(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// decodeObject walks a JSON object from a streaming decoder, calling fn with each
+// key. fn must consume the key's value from the decoder.
+func decodeObject(dec *json.Decoder, fn func(key string) error) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '{' {
+		return fmt.Errorf("expected a JSON object, found %v", tok)
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		if err := fn(tok.(string)); err != nil {
+			return err
+		}
+	}
+	_, err = dec.Token()
+	return err
+}
+
+// skipValue consumes the next JSON value from a streaming decoder
+func skipValue(dec *json.Decoder) error {
+	var raw json.RawMessage
+	return dec.Decode(&raw)
+}
+
+func init() {
+	catalogCmd.AddCommand(catalogImportCmd)
+	rootCmd.AddCommand(catalogCmd)
+}
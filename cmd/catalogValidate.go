@@ -0,0 +1,388 @@
+package cmd
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// catalogSchema is the published JSON Schema of the catalog format
+//
+//go:embed data/catalog.schema.json
+var catalogSchema []byte
+
+// catalogUnits are the units a catalog SKU can be priced in
+var catalogUnits = []string{"hour", "month", "gb-month", "gb", "request"}
+
+// catalogModels are the pricing models a catalog SKU can have besides on-demand
+var catalogModels = []string{"reserved-1y", "reserved-3y", "savings-plan-1y", "savings-plan-3y", "cud-1y", "cud-3y", "spot"}
+
+// catalogIssue is a problem found while validating a catalog, located by line and column
+type catalogIssue struct {
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+	Severity string `json:"severity"`
+	Path     string `json:"path,omitempty"`
+	Message  string `json:"message"`
+}
+
+// format formats the issue like a compiler diagnostic, prefixed with the file name
+func (i catalogIssue) format(file string) string {
+	s := fmt.Sprintf("%s:%d:%d: %s: %s", file, i.Line, i.Column, i.Severity, i.Message)
+	if i.Path != "" {
+		s += " (" + i.Path + ")"
+	}
+	return s
+}
+
+// catalogError is returned when a catalog has validation errors
+type catalogError struct {
+	issues []catalogIssue
+}
+
+func (e catalogError) Error() string {
+	var errs []string
+	for _, i := range e.issues {
+		if i.Severity == "error" {
+			errs = append(errs, fmt.Sprintf("line %d, column %d: %s", i.Line, i.Column, i.Message))
+		}
+	}
+	if len(errs) > 3 {
+		errs = append(errs[:3], fmt.Sprintf("and %d more, run 'cloudcents catalog validate' for details", len(errs)-3))
+	}
+	return "invalid catalog:\n" + strings.Join(errs, "\n")
+}
+
+// catalogValidateCmd validates a catalog file
+var catalogValidateCmd = &cobra.Command{
+	Use:   "validate <file>",
+	Short: "Validate and lint a pricing catalog file",
+	Long: `Validate a pricing catalog against the catalog schema and lint it for
+mistakes that would otherwise show up as wrong prices: unknown fields, prices
+that are not numbers, zero or negative prices, duplicate SKUs and sizes that
+only some providers offer (often a typo). Print the schema with 'catalog schema'.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		data, err := os.ReadFile(args[0])
+		if err != nil {
+			return fmt.Errorf("reading catalog: %v", err)
+		}
+
+		_, issues := validateCatalog(data)
+		errorCount := 0
+		for _, i := range issues {
+			if i.Severity == "error" {
+				errorCount++
+			}
+		}
+
+		format, err := getOutputFormat(cmd)
+		if err != nil {
+			return err
+		}
+		switch format {
+		case "json":
+			err = writeJSON(os.Stdout, issues)
+		case "yaml":
+			err = writeYAML(os.Stdout, issues)
+		default:
+			for _, i := range issues {
+				style := infoStyle
+				if i.Severity == "error" {
+					style = errorStyle
+				}
+				fmt.Println(style.Render(i.format(args[0])))
+			}
+			if errorCount == 0 {
+				displaySuccess(fmt.Sprintf("'%s' is a valid catalog (%d warnings)", args[0], len(issues)))
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("writing issues: %v", err)
+		}
+		if errorCount > 0 {
+			// The issues are the report, so exit without another error message
+			return withExitCode(exitFailure, nil)
+		}
+		return nil
+	},
+}
+
+// catalogSchemaCmd prints the catalog JSON Schema
+var catalogSchemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print the JSON Schema of the pricing catalog format",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		_, err := os.Stdout.Write(catalogSchema)
+		return err
+	},
+}
+
+// validateCatalog parses a catalog and returns it with every issue found, in file order.
+// Syntax errors, unknown fields and mistyped values stop validation early.
+func validateCatalog(data []byte) (Catalog, []catalogIssue) {
+	var c Catalog
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&c); err != nil {
+		offset := dec.InputOffset()
+		var syntaxErr *json.SyntaxError
+		var typeErr *json.UnmarshalTypeError
+		message := err.Error()
+		switch {
+		case errors.As(err, &syntaxErr):
+			offset = syntaxErr.Offset
+			message = "invalid JSON: " + syntaxErr.Error()
+		case errors.As(err, &typeErr):
+			// The error's offset is past the value, so point at its start when it can be found
+			offset = typeErr.Offset
+			path := jsonIndex.ReplaceAllString(typeErr.Field, "[$1]")
+			if start, ok := jsonPositions(data)[path]; ok {
+				offset = start
+			}
+			message = fmt.Sprintf("%s must be %s, found a %s", path, jsonTypeName(typeErr.Type.Kind().String()), typeErr.Value)
+		case errors.Is(err, io.EOF):
+			message = "catalog is empty"
+		case errors.Is(err, io.ErrUnexpectedEOF):
+			offset = int64(len(data))
+			message = "invalid JSON: unexpected end of file"
+		case strings.HasPrefix(message, "json: unknown field "):
+			// Unknown field errors carry no offset, so point at the first use of the field
+			field := strings.TrimPrefix(message, "json: unknown field ")
+			if loc := regexp.MustCompile(regexp.QuoteMeta(field) + `\s*:`).FindIndex(data); loc != nil {
+				offset = int64(loc[0])
+			}
+			message = "unknown field " + field
+		}
+		line, col := lineColumn(data, offset)
+		return c, []catalogIssue{{Line: line, Column: col, Severity: "error", Message: message}}
+	}
+
+	positions := jsonPositions(data)
+	var issues []catalogIssue
+	report := func(severity, path, format string, args ...interface{}) {
+		line, col := lineColumn(data, positions[path])
+		issues = append(issues, catalogIssue{Line: line, Column: col, Severity: severity, Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if len(c.Providers) == 0 {
+		report("error", "", "catalog has no providers")
+	}
+
+	providers := map[string]bool{}
+	for pi, p := range c.Providers {
+		pPath := fmt.Sprintf("providers[%d]", pi)
+		switch {
+		case p.Name == "":
+			report("error", pPath, "provider has no name")
+		case providers[p.Name]:
+			report("error", pPath, "duplicate provider %q", p.Name)
+		}
+		providers[p.Name] = true
+
+		services := map[string]bool{}
+		for si, s := range p.Services {
+			sPath := fmt.Sprintf("%s.services[%d]", pPath, si)
+			switch {
+			case s.Name == "":
+				report("error", sPath, "service has no name")
+			case services[s.Name]:
+				report("error", sPath, "duplicate service %q of provider %q", s.Name, p.Name)
+			}
+			services[s.Name] = true
+
+			skus := map[string]bool{}
+			for ki, sku := range s.SKUs {
+				kPath := fmt.Sprintf("%s.skus[%d]", sPath, ki)
+				if sku.Size == "" {
+					report("error", kPath, "SKU has no size")
+				}
+				if sku.Price <= 0 {
+					report("error", kPath+".price", "price of %s %s %s must be positive, found %g", p.Name, s.Name, skuName(sku), sku.Price)
+				}
+				if sku.Unit != "" && !contains(catalogUnits, sku.Unit) {
+					report("warning", kPath+".unit", "unknown unit %q, expected one of %s", sku.Unit, strings.Join(catalogUnits, ", "))
+				}
+				if sku.Model != "" && !contains(catalogModels, sku.Model) {
+					report("warning", kPath+".model", "unknown pricing model %q, expected one of %s", sku.Model, strings.Join(catalogModels, ", "))
+				}
+
+				key := strings.Join([]string{skuName(sku), sku.Region, sku.Model}, "|")
+				if skus[key] {
+					report("error", kPath, "duplicate SKU %s of %s %s (same region and pricing model)", skuName(sku), p.Name, s.Name)
+				}
+				skus[key] = true
+			}
+		}
+	}
+
+	checkMissingSizes(c, report)
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].Line != issues[j].Line {
+			return issues[i].Line < issues[j].Line
+		}
+		return issues[i].Column < issues[j].Column
+	})
+	return c, issues
+}
+
+// checkMissingSizes warns about sizes that only some of the providers offering a
+// service have, which is how a typo like "medum" shows up
+func checkMissingSizes(c Catalog, report func(severity, path, format string, args ...interface{})) {
+	for _, service := range c.serviceNames() {
+		var offering []string
+		for _, p := range c.Providers {
+			if p.service(service) != nil {
+				offering = append(offering, p.Name)
+			}
+		}
+		if len(offering) < 2 {
+			continue
+		}
+
+		for _, size := range c.sizeNames(service) {
+			var missing []string
+			path := ""
+			for pi, p := range c.Providers {
+				s := p.service(service)
+				if s == nil {
+					continue
+				}
+				found := false
+				for ki, sku := range s.SKUs {
+					if sku.Size == size {
+						found = true
+						if path == "" {
+							path = fmt.Sprintf("providers[%d].services[%d].skus[%d].size", pi, serviceIndex(p, service), ki)
+						}
+						break
+					}
+				}
+				if !found {
+					missing = append(missing, p.Name)
+				}
+			}
+			if len(missing) > 0 {
+				report("warning", path, "size %q of %s is missing for %s", size, service, strings.Join(missing, ", "))
+			}
+		}
+	}
+}
+
+// jsonIndex matches the array indexes in the field paths of encoding/json errors, e.g. ".0."
+var jsonIndex = regexp.MustCompile(`\.(\d+)\b`)
+
+// jsonTypeName names a Go kind by the JSON type it decodes from
+func jsonTypeName(kind string) string {
+	switch kind {
+	case "float64", "float32", "int", "int64":
+		return "a number"
+	case "map", "struct":
+		return "an object"
+	case "slice":
+		return "an array"
+	}
+	return "a " + kind
+}
+
+// serviceIndex returns the index of a service in a provider
+func serviceIndex(p Provider, name string) int {
+	for i, s := range p.Services {
+		if s.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// skuName names a SKU in messages by its ID, or by its size when it has none
+func skuName(sku SKU) string {
+	if sku.ID != "" {
+		return sku.ID
+	}
+	return sku.Size
+}
+
+// contains reports whether list contains s
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// lineColumn converts a byte offset into a 1-based line and column
+func lineColumn(data []byte, offset int64) (int, int) {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	before := data[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	col := len(before) - bytes.LastIndexByte(before, '\n')
+	return line, col
+}
+
+// jsonPositions maps the path of every value in a JSON document, e.g.
+// "providers[0].services[1].skus[2].price", to the byte offset where it starts
+func jsonPositions(data []byte) map[string]int64 {
+	positions := map[string]int64{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+
+	// valueStart skips the separators between the previous token and the next value
+	valueStart := func() int64 {
+		offset := dec.InputOffset()
+		for offset < int64(len(data)) && strings.ContainsRune(" \t\r\n:,", rune(data[offset])) {
+			offset++
+		}
+		return offset
+	}
+
+	var walk func(path string) error
+	walk = func(path string) error {
+		positions[path] = valueStart()
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		delim, ok := tok.(json.Delim)
+		if !ok {
+			return nil
+		}
+		for i := 0; dec.More(); i++ {
+			child := fmt.Sprintf("%s[%d]", path, i)
+			if delim == '{' {
+				key, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				child = fmt.Sprintf("%s.%v", path, key)
+				if path == "" {
+					child = fmt.Sprint(key)
+				}
+			}
+			if err := walk(child); err != nil {
+				return err
+			}
+		}
+		_, err = dec.Token()
+		return err
+	}
+	walk("")
+	return positions
+}
+
+func init() {
+	catalogCmd.AddCommand(catalogValidateCmd)
+	catalogCmd.AddCommand(catalogSchemaCmd)
+}
@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+// lintCatalog is a valid catalog whose medium size only AWS offers
+const lintCatalog = `{
+  "providers": [
+    {
+      "name": "aws",
+      "services": [
+        {
+          "name": "compute",
+          "skus": [
+            {"id": "a1", "size": "small", "unit": "hour", "price": 0.1},
+            {"id": "a2", "size": "medium", "unit": "hour", "price": 0.2}
+          ]
+        }
+      ]
+    },
+    {
+      "name": "gcp",
+      "services": [
+        {
+          "name": "compute",
+          "skus": [
+            {"id": "g1", "size": "small", "unit": "hour", "price": 0.1}
+          ]
+        }
+      ]
+    }
+  ]
+}
+`
+
+func TestValidateCatalog(t *testing.T) {
+	missingMedium := catalogIssue{Line: 10, Column: 34, Severity: "warning", Path: "providers[0].services[0].skus[1].size",
+		Message: `size "medium" of compute is missing for gcp`}
+	tests := []struct {
+		name     string
+		old, new string // replaced in lintCatalog
+		issues   []catalogIssue
+	}{
+		{"missing size", "", "", []catalogIssue{missingMedium}},
+		{"string price", `"price": 0.2}`, `"price": "0.2"}`, []catalogIssue{
+			{Line: 10, Column: 69, Severity: "error", Message: "providers[0].services[0].skus[1].price must be a number, found a string"},
+		}},
+		{"unknown field", `{"id": "a1", "size"`, `{"id": "a1", "colour": "red", "size"`, []catalogIssue{
+			{Line: 9, Column: 26, Severity: "error", Message: `unknown field "colour"`},
+		}},
+		{"duplicate SKU", `{"id": "a2"`, `{"id": "a1"`, []catalogIssue{
+			{Line: 10, Column: 13, Severity: "error", Path: "providers[0].services[0].skus[1]", Message: "duplicate SKU a1 of aws compute (same region and pricing model)"},
+			missingMedium,
+		}},
+		{"zero price", `"small", "unit": "hour", "price": 0.1},`, `"small", "unit": "hour", "price": 0},`, []catalogIssue{
+			{Line: 9, Column: 68, Severity: "error", Path: "providers[0].services[0].skus[0].price", Message: "price of aws compute a1 must be positive, found 0"},
+			missingMedium,
+		}},
+		{"truncated JSON", lintCatalog[200:], "", []catalogIssue{
+			{Line: 10, Column: 3, Severity: "error", Message: "invalid JSON: unexpected end of file"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := lintCatalog
+			if tt.old != "" {
+				if !strings.Contains(data, tt.old) {
+					t.Fatalf("the catalog has no %q", tt.old)
+				}
+				data = strings.Replace(data, tt.old, tt.new, 1)
+			}
+			_, issues := validateCatalog([]byte(data))
+			if len(issues) != len(tt.issues) {
+				t.Fatalf("got issues %+v, want %+v", issues, tt.issues)
+			}
+			for i, want := range tt.issues {
+				if issues[i] != want {
+					t.Errorf("got issue %+v, want %+v", issues[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestLineColumn(t *testing.T) {
+	data := []byte("{\n  \"a\": 1,\n  \"b\": 2\n}")
+	tests := []struct {
+		offset    int64
+		line, col int
+	}{
+		{0, 1, 1},
+		{1, 1, 2},
+		{2, 2, 1},
+		{9, 2, 8},
+		{14, 3, 3},
+		{int64(len(data)), 4, 2},
+		{1000, 4, 2},
+	}
+	for _, tt := range tests {
+		if line, col := lineColumn(data, tt.offset); line != tt.line || col != tt.col {
+			t.Errorf("lineColumn(%d) = %d:%d, want %d:%d", tt.offset, line, col, tt.line, tt.col)
+		}
+	}
+}
+
+func TestJSONPositions(t *testing.T) {
+	positions := jsonPositions([]byte(lintCatalog))
+	for path, want := range map[string][2]int{
+		"providers":                              {2, 16},
+		"providers[1].name":                      {16, 15},
+		"providers[0].services[0].skus[1]":       {10, 13},
+		"providers[0].services[0].skus[1].price": {10, 69},
+	} {
+		offset, ok := positions[path]
+		if !ok {
+			t.Errorf("no position for %s", path)
+			continue
+		}
+		if line, col := lineColumn([]byte(lintCatalog), offset); line != want[0] || col != want[1] {
+			t.Errorf("%s is at %d:%d, want %d:%d", path, line, col, want[0], want[1])
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import "testing"
+
+func TestCatalogMerge(t *testing.T) {
+	c, _, err := parseCatalog(defaultCatalog)
+	if err != nil {
+		t.Fatalf("parsing the built-in catalog: %v", err)
+	}
+	before := skuCounts(*c.provider("aws"))
+	imported := parseAWSFixture(t, "ec2-us-east-1.json")
+	c.merge(imported)
+	aws := *c.provider("aws")
+
+	// The built-in sizes of the imported region are kept next to the imported SKUs
+	for _, size := range []string{"small", "medium", "large"} {
+		findSKU(t, aws, "compute", "aws-compute-"+size, "us-east-1", "")
+	}
+	assertSKU(t, findSKU(t, aws, "compute", "m5.large", "us-east-1", "reserved-1y"), "2vcpu-8gb", "hour", 0.06)
+	// gp2, gp3 and io2 are in both, and the imported prices replace the built-in ones
+	assertSKU(t, findSKU(t, aws, "block-storage", "gp3", "us-east-1", ""), "ssd", "gb-month", 0.08)
+	counts := skuCounts(aws)
+	if counts["compute"] != before["compute"]+15 || counts["block-storage"] != before["block-storage"]+2 {
+		t.Errorf("got SKU counts %v after the import, had %v", counts, before)
+	}
+
+	// Importing again updates prices without adding SKUs
+	for i := range imported.Services {
+		for j := range imported.Services[i].SKUs {
+			imported.Services[i].SKUs[j].Price *= 2
+		}
+	}
+	c.merge(imported)
+	aws = *c.provider("aws")
+	if got := skuCounts(aws); got["compute"] != counts["compute"] || got["block-storage"] != counts["block-storage"] {
+		t.Errorf("got SKU counts %v after importing again, want %v", got, counts)
+	}
+	assertSKU(t, findSKU(t, aws, "compute", "m5.large", "us-east-1", ""), "2vcpu-8gb", "hour", 0.192)
+
+	// SKUs without an ID are told apart by size, region and pricing model
+	c.merge(Provider{Name: "aws", Services: []Service{{Name: "network", SKUs: []SKU{
+		{Size: "vpn", Region: "us-east-1", Unit: "hour", Price: 0.05},
+		{Size: "vpn", Region: "eu-west-1", Unit: "hour", Price: 0.06},
+	}}}})
+	c.merge(Provider{Name: "aws", Services: []Service{{Name: "network", SKUs: []SKU{
+		{Size: "vpn", Region: "us-east-1", Unit: "hour", Price: 0.04},
+	}}}})
+	vpn := map[string]float64{}
+	for _, sku := range c.provider("aws").service("network").SKUs {
+		if sku.Size == "vpn" {
+			vpn[sku.Region] = sku.Price
+		}
+	}
+	if len(vpn) != 2 || vpn["us-east-1"] != 0.04 || vpn["eu-west-1"] != 0.06 {
+		t.Errorf("got VPN prices %v", vpn)
+	}
+}
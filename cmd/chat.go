@@ -16,12 +16,13 @@ var chatCmd = &cobra.Command{
 	Use:   "chat [prompt]",
 	Short: "Send a dynamic prompt to the Cloud Cents API",
 	Args:  cobra.ExactArgs(1), // Ensure exactly one argument is provided (the prompt)
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		prompt := args[0]
 		p := tea.NewProgram(chatModel{prompt: prompt})
 		if err := p.Start(); err != nil {
-			fmt.Printf("Error starting program: %v\n", err)
+			return fmt.Errorf("starting program: %v", err)
 		}
+		return nil
 	},
 }
 
@@ -103,7 +104,7 @@ func (m chatModel) sendAPIRequest(prompt string) tea.Cmd {
 		escapedPrompt := strings.ReplaceAll(prompt, " ", "%20")
 
 		// Define the URL with the dynamic prompt
-		url := fmt.Sprintf("https://cloud-cents.onrender.com/generate/test/%s?max_length=200", escapedPrompt)
+		url := fmt.Sprintf("%s/generate/test/%s?max_length=200", strings.TrimRight(configValue("api_url"), "/"), escapedPrompt)
 		req, err := http.NewRequest("GET", url, nil)
 		if err != nil {
 			return "Error creating request"
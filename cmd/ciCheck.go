@@ -0,0 +1,390 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// ciCmd groups the commands meant to run in CI pipelines
+var ciCmd = &cobra.Command{
+	Use:   "ci",
+	Short: "Cost checks for CI pipelines",
+}
+
+// ciCheckCmd compares two estimates and fails when the cost increases too much
+var ciCheckCmd = &cobra.Command{
+	Use:   "check --baseline old.json --current new.json",
+	Short: "Compare two estimates and fail when the monthly cost increases too much",
+	Long: `Compare two estimates written with 'estimate --output json' (workload,
+Terraform plan or Kubernetes estimates) and print a Markdown summary for a pull
+request comment.
+
+The gated cost is the total on --provider. Without it, Terraform estimates use
+the cost as planned (every resource on the provider it is defined for) and
+other estimates use the provider that is cheapest in the baseline. Estimates
+with resources that aren't priced, or without a total on the gated provider,
+fail the check, since their cost is unknown.
+
+--max-increase is a percentage ("10%") or an amount a month ("50" or "$50").
+When the increase is over it, the command exits with code 3.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		baselinePath, _ := cmd.Flags().GetString("baseline")
+		currentPath, _ := cmd.Flags().GetString("current")
+		if baselinePath == "" || currentPath == "" {
+			return withExitCode(exitUsage, fmt.Errorf("both --baseline and --current are needed"))
+		}
+		maxIncrease, _ := cmd.Flags().GetString("max-increase")
+		limit, err := parseCostLimit(maxIncrease)
+		if err != nil {
+			return withExitCode(exitUsage, err)
+		}
+		provider, _ := cmd.Flags().GetString("provider")
+
+		format, err := getOutputFormat(cmd)
+		if err != nil {
+			return err
+		}
+		// Only provider labels come from the catalog
+		if err := loadPricingData(cmd); err != nil {
+			return err
+		}
+
+		baseline, err := readEstimateFile(baselinePath)
+		if err != nil {
+			return err
+		}
+		current, err := readEstimateFile(currentPath)
+		if err != nil {
+			return err
+		}
+
+		if baseline.Currency != current.Currency {
+			return fmt.Errorf("the baseline is in %s and the current estimate in %s, write both with the same --currency", baseline.Currency, current.Currency)
+		}
+		// Amounts are shown in the estimates' currency
+		displayCurrency = baseline.Currency
+
+		result, err := checkCost(baseline, current, provider, limit)
+		if err != nil {
+			return err
+		}
+		switch format {
+		case "json":
+			err = writeJSON(os.Stdout, result)
+		case "yaml":
+			err = writeYAML(os.Stdout, result)
+		default:
+			err = writeCostCheckMarkdown(os.Stdout, result)
+		}
+		if err != nil {
+			return fmt.Errorf("writing summary: %v", err)
+		}
+
+		if result.Exceeded {
+			// The summary says why, so exit without another error message
+			return withExitCode(exitBudgetExceeded, nil)
+		}
+		return nil
+	},
+}
+
+// costLimit is the largest monthly cost increase ci check accepts
+type costLimit struct {
+	value   float64
+	percent bool
+	set     bool
+}
+
+// String formats a cost limit the way it was given
+func (l costLimit) String() string {
+	if l.percent {
+		return strconv.FormatFloat(l.value, 'f', -1, 64) + "%"
+	}
+	return formatMoney(l.value)
+}
+
+// exceeded reports whether a change from a baseline cost is over the limit. Any
+// increase from nothing is over a percentage limit.
+func (l costLimit) exceeded(baseline, change float64) bool {
+	switch {
+	case !l.set:
+		return false
+	case !l.percent:
+		return change > l.value
+	case baseline == 0:
+		return change > 0
+	}
+	return change/baseline*100 > l.value
+}
+
+// parseCostLimit parses a --max-increase value: a percentage such as "10%" or a
+// monthly amount such as "50" or "$50". An empty value is no limit.
+func parseCostLimit(s string) (costLimit, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return costLimit{}, nil
+	}
+	percent := strings.HasSuffix(s, "%")
+	v, err := strconv.ParseFloat(strings.TrimPrefix(strings.TrimSuffix(s, "%"), "$"), 64)
+	if err != nil || v < 0 {
+		return costLimit{}, fmt.Errorf("invalid --max-increase %q, expected a percentage such as 10%% or an amount such as 50", s)
+	}
+	return costLimit{value: v, percent: percent, set: true}, nil
+}
+
+// readEstimateFile reads an estimate written with --output json. For Terraform plan
+// estimates the planned state is used.
+func readEstimateFile(path string) (estimate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return estimate{}, fmt.Errorf("reading estimate: %v", err)
+	}
+	var file struct {
+		estimate
+		Planned *estimate `json:"planned"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return estimate{}, fmt.Errorf("parsing estimate '%s', expected 'estimate --output json' output: %v", path, err)
+	}
+	est := file.estimate
+	if file.Planned != nil {
+		est = *file.Planned
+	}
+	if est.Items == nil {
+		return estimate{}, fmt.Errorf("'%s' is not an estimate, write one with 'estimate --output json'", path)
+	}
+	// Estimates written before --currency existed are in the catalog currency
+	if est.Currency == "" {
+		est.Currency = catalogCurrency
+	}
+	return est, nil
+}
+
+// costCheck is the result of comparing a current estimate with a baseline
+type costCheck struct {
+	Gate        string           `json:"gate" yaml:"gate"`
+	Baseline    float64          `json:"baseline" yaml:"baseline"`
+	Current     float64          `json:"current" yaml:"current"`
+	Change      float64          `json:"change" yaml:"change"`
+	ChangePct   *float64         `json:"change_percent,omitempty" yaml:"change_percent,omitempty"`
+	MaxIncrease string           `json:"max_increase,omitempty" yaml:"max_increase,omitempty"`
+	Exceeded    bool             `json:"exceeded" yaml:"exceeded"`
+	Providers   []providerChange `json:"providers" yaml:"providers"`
+	ItemChanges []itemChange     `json:"item_changes" yaml:"item_changes"`
+}
+
+// providerChange is the change of a provider's total between two estimates
+type providerChange struct {
+	Provider string   `json:"provider" yaml:"provider"`
+	Baseline *float64 `json:"baseline" yaml:"baseline"`
+	Current  *float64 `json:"current" yaml:"current"`
+}
+
+// itemChange is the change of a line item's gated cost between two estimates
+type itemChange struct {
+	Name     string  `json:"name" yaml:"name"`
+	Baseline float64 `json:"baseline" yaml:"baseline"`
+	Current  float64 `json:"current" yaml:"current"`
+	Change   float64 `json:"change" yaml:"change"`
+}
+
+// hasHomeProviders reports whether the items of an estimate are defined for a
+// provider, as the resources of a Terraform plan are
+func hasHomeProviders(est estimate) bool {
+	for _, item := range est.Items {
+		if item.Provider != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// checkCost compares the gated cost of two estimates against a limit. It fails
+// rather than gate on a total that leaves out unpriced resources.
+func checkCost(baseline, current estimate, provider string, limit costLimit) (costCheck, error) {
+	home := hasHomeProviders(baseline) || hasHomeProviders(current)
+	if provider == "" && !home {
+		provider = baseline.Cheapest
+		if provider == "" {
+			return costCheck{}, fmt.Errorf("no provider can price every item of the baseline, choose one with --provider")
+		}
+	}
+
+	check := costCheck{Gate: "as planned"}
+	if provider != "" {
+		check.Gate = provider
+	}
+	cost := func(which string, est estimate) (float64, error) {
+		if len(est.Unpriced) > 0 {
+			return 0, fmt.Errorf("the %s estimate has resources that aren't priced, so its cost is unknown:\n  %s",
+				which, strings.Join(est.Unpriced, "\n  "))
+		}
+		if provider == "" {
+			if est.HomeTotal == nil {
+				return 0, fmt.Errorf("the %s estimate has no cost as planned", which)
+			}
+			return *est.HomeTotal, nil
+		}
+		total, ok := est.Totals[provider]
+		if !ok {
+			return 0, fmt.Errorf("the %s estimate has no total on %s, which doesn't offer every resource", which, providerLabel(provider))
+		}
+		return total, nil
+	}
+	var err error
+	if check.Baseline, err = cost("baseline", baseline); err != nil {
+		return check, err
+	}
+	if check.Current, err = cost("current", current); err != nil {
+		return check, err
+	}
+
+	check.Change = roundPrice(check.Current - check.Baseline)
+	if check.Baseline != 0 {
+		pct := math.Round(check.Change/check.Baseline*1000) / 10
+		check.ChangePct = &pct
+	}
+	if limit.set {
+		check.MaxIncrease = limit.String()
+		check.Exceeded = limit.exceeded(check.Baseline, check.Change)
+	}
+
+	// Totals of every provider in either estimate
+	seen := map[string]bool{}
+	for _, p := range append(append([]string{}, baseline.Providers...), current.Providers...) {
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
+		pc := providerChange{Provider: p}
+		if v, ok := baseline.Totals[p]; ok {
+			pc.Baseline = &v
+		}
+		if v, ok := current.Totals[p]; ok {
+			pc.Current = &v
+		}
+		check.Providers = append(check.Providers, pc)
+	}
+
+	// Items matched by name, largest change first
+	itemCost := func(item estimateItem) float64 {
+		if provider == "" {
+			return item.Costs[item.Provider]
+		}
+		return item.Costs[provider]
+	}
+	changes := map[string]*itemChange{}
+	var names []string
+	add := func(items []estimateItem, current bool) {
+		for _, item := range items {
+			name := strings.TrimLeft(item.Name, "+-~/ ")
+			c, ok := changes[name]
+			if !ok {
+				c = &itemChange{Name: name}
+				changes[name] = c
+				names = append(names, name)
+			}
+			if current {
+				c.Current += itemCost(item)
+			} else {
+				c.Baseline += itemCost(item)
+			}
+		}
+	}
+	add(baseline.Items, false)
+	add(current.Items, true)
+	for _, name := range names {
+		c := changes[name]
+		c.Baseline, c.Current = roundPrice(c.Baseline), roundPrice(c.Current)
+		c.Change = roundPrice(c.Current - c.Baseline)
+		if c.Change != 0 {
+			check.ItemChanges = append(check.ItemChanges, *c)
+		}
+	}
+	sort.SliceStable(check.ItemChanges, func(i, j int) bool {
+		return math.Abs(check.ItemChanges[i].Change) > math.Abs(check.ItemChanges[j].Change)
+	})
+	return check, nil
+}
+
+// maxItemChanges is how many item changes the Markdown summary lists
+const maxItemChanges = 10
+
+// writeCostCheckMarkdown writes a cost check as a Markdown summary for a pull request comment
+func writeCostCheckMarkdown(w io.Writer, check costCheck) error {
+	gate := "As planned"
+	if check.Gate != "as planned" {
+		gate = providerLabel(check.Gate)
+	}
+	fmt.Fprintln(w, "### Cloud cost check")
+	fmt.Fprintln(w)
+
+	switch {
+	case check.MaxIncrease == "":
+		fmt.Fprintf(w, "Monthly cost (%s): %s → %s, %s\n", gate, formatMoney(check.Baseline), formatMoney(check.Current), formatDelta(check.Baseline, check.Current))
+	case check.Exceeded:
+		fmt.Fprintf(w, "❌ Monthly cost (%s) goes from %s to %s, %s, over the %s limit.\n", gate, formatMoney(check.Baseline), formatMoney(check.Current), formatDelta(check.Baseline, check.Current), check.MaxIncrease)
+	default:
+		fmt.Fprintf(w, "✅ Monthly cost (%s) goes from %s to %s, %s, within the %s limit.\n", gate, formatMoney(check.Baseline), formatMoney(check.Current), formatDelta(check.Baseline, check.Current), check.MaxIncrease)
+	}
+	fmt.Fprintln(w)
+
+	money := func(v *float64) string {
+		if v == nil {
+			return "-"
+		}
+		return formatMoney(*v)
+	}
+	var rows [][]string
+	for _, pc := range check.Providers {
+		name := providerLabel(pc.Provider)
+		if pc.Provider == check.Gate {
+			name = "**" + name + "**"
+		}
+		change := "-"
+		if pc.Baseline != nil && pc.Current != nil {
+			change = formatDelta(*pc.Baseline, *pc.Current)
+		}
+		rows = append(rows, []string{name, money(pc.Baseline), money(pc.Current), change})
+	}
+	if err := writeMarkdownTable(w, []string{"Provider", currencyHeader("Baseline", "/mo"), currencyHeader("Current", "/mo"), "Change"}, rows); err != nil {
+		return err
+	}
+
+	if len(check.ItemChanges) == 0 {
+		return nil
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "<details><summary>Changed items (%s)</summary>\n\n", gate)
+	rows = nil
+	for i, c := range check.ItemChanges {
+		if i == maxItemChanges {
+			rows = append(rows, []string{fmt.Sprintf("and %d more", len(check.ItemChanges)-maxItemChanges), "", "", ""})
+			break
+		}
+		rows = append(rows, []string{c.Name, formatMoney(c.Baseline), formatMoney(c.Current), formatDelta(c.Baseline, c.Current)})
+	}
+	if err := writeMarkdownTable(w, []string{"Item", currencyHeader("Baseline", "/mo"), currencyHeader("Current", "/mo"), "Change"}, rows); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintln(w, "\n</details>")
+	return err
+}
+
+func init() {
+	ciCheckCmd.Flags().String("baseline", "", "Estimate JSON of the base branch")
+	ciCheckCmd.Flags().String("current", "", "Estimate JSON of the change")
+	ciCheckCmd.Flags().String("max-increase", "", "Largest accepted monthly increase, e.g. 10% or 50 (default: no limit)")
+	ciCheckCmd.Flags().String("provider", "", "Provider whose total is gated (default: as planned, or the cheapest in the baseline)")
+	ciCmd.AddCommand(ciCheckCmd)
+	rootCmd.AddCommand(ciCmd)
+}
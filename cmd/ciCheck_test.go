@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseCostLimit(t *testing.T) {
+	tests := []struct {
+		s     string
+		limit costLimit
+		err   bool
+	}{
+		{s: "", limit: costLimit{}},
+		{s: "10%", limit: costLimit{value: 10, percent: true, set: true}},
+		{s: " 12.5% ", limit: costLimit{value: 12.5, percent: true, set: true}},
+		{s: "50", limit: costLimit{value: 50, set: true}},
+		{s: "$50", limit: costLimit{value: 50, set: true}},
+		{s: "0", limit: costLimit{value: 0, set: true}},
+		{s: "-5", err: true},
+		{s: "ten", err: true},
+		{s: "$", err: true},
+		{s: "10%%", err: true},
+	}
+	for _, tt := range tests {
+		limit, err := parseCostLimit(tt.s)
+		if tt.err {
+			if err == nil {
+				t.Errorf("parseCostLimit(%q) = %+v, want an error", tt.s, limit)
+			}
+			continue
+		}
+		if err != nil || limit != tt.limit {
+			t.Errorf("parseCostLimit(%q) = %+v, %v; want %+v", tt.s, limit, err, tt.limit)
+		}
+	}
+}
+
+func TestCostLimitExceeded(t *testing.T) {
+	tests := []struct {
+		limit            string
+		baseline, change float64
+		want             bool
+	}{
+		{"", 100, 1000, false},
+		{"10%", 100, 10, false},
+		{"10%", 100, 10.5, true},
+		{"10%", 100, -50, false},
+		{"10%", 0, 1, true},
+		{"10%", 0, 0, false},
+		{"50", 100, 50, false},
+		{"$50", 100, 50.01, true},
+		{"0", 100, 0.01, true},
+	}
+	for _, tt := range tests {
+		limit, err := parseCostLimit(tt.limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := limit.exceeded(tt.baseline, tt.change); got != tt.want {
+			t.Errorf("limit %q exceeded by %v from %v = %v, want %v", tt.limit, tt.change, tt.baseline, got, tt.want)
+		}
+	}
+}
+
+// planned returns an estimate of items defined for AWS, like a Terraform plan estimate
+func planned(costs ...float64) estimate {
+	est := estimate{Providers: []string{"aws", "gcp"}, Totals: map[string]float64{}}
+	total := 0.0
+	for _, cost := range costs {
+		est.Items = append(est.Items, estimateItem{Name: "disk", Provider: "aws", Costs: map[string]float64{"aws": cost, "gcp": cost * 2}})
+		total += cost
+	}
+	est.Totals["aws"], est.Totals["gcp"] = total, total*2
+	est.HomeTotal = &total
+	est.Cheapest = "aws"
+	return est
+}
+
+func TestCheckCost(t *testing.T) {
+	useBuiltinCatalog(t)
+	limit, _ := parseCostLimit("10%")
+
+	check, err := checkCost(planned(100), planned(100, 20), "", limit)
+	if err != nil {
+		t.Fatalf("checkCost: %v", err)
+	}
+	if check.Gate != "as planned" || check.Change != 20 || !check.Exceeded {
+		t.Errorf("got %+v, want an exceeded as planned gate with a change of 20", check)
+	}
+
+	// Plans that cost nothing are still gated as planned
+	check, err = checkCost(planned(0), planned(0), "", limit)
+	if err != nil || check.Gate != "as planned" || check.Exceeded {
+		t.Errorf("got %+v, %v; want a passing as planned gate", check, err)
+	}
+
+	// An unpriced resource fails the check instead of looking like a cost decrease
+	current := planned(50)
+	current.HomeTotal = nil
+	current.Unpriced = []string{"+ aws_ebs_volume.data: no block-storage price on AWS"}
+	if _, err := checkCost(planned(100), current, "", limit); err == nil || !strings.Contains(err.Error(), "aws_ebs_volume.data") {
+		t.Errorf("got error %v, want one naming the unpriced resource", err)
+	}
+	if _, err := checkCost(planned(100), current, "gcp", limit); err == nil {
+		t.Error("got no error gating on a provider with an unpriced resource")
+	}
+
+	// A gated provider without a total fails the check
+	current = planned(100)
+	delete(current.Totals, "gcp")
+	current.Incomplete = []string{"gcp"}
+	if _, err := checkCost(planned(100), current, "gcp", limit); err == nil {
+		t.Error("got no error gating on an incomplete provider")
+	}
+}
+
+func TestCICheckExitCode(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("APPDATA", home)
+	write := func(name string, est estimate) string {
+		est.Currency = catalogCurrency
+		data, err := json.Marshal(est)
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(home, name)
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	baseline := write("baseline.json", planned(100))
+
+	// The summary goes to stdout
+	stdout := os.Stdout
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stdout = devNull
+	t.Cleanup(func() {
+		os.Stdout = stdout
+		devNull.Close()
+	})
+
+	tests := []struct {
+		current float64
+		limit   string
+		code    int
+	}{
+		{105, "10%", exitOK},
+		{120, "10%", exitBudgetExceeded},
+		{120, "$25", exitOK},
+		{130, "$25", exitBudgetExceeded},
+	}
+	for _, tt := range tests {
+		current := write("current.json", planned(tt.current))
+		rootCmd.SetArgs([]string{"ci", "check", "--baseline", baseline, "--current", current, "--max-increase", tt.limit, "--output", "json"})
+		err := rootCmd.Execute()
+		code := exitOK
+		var coded *exitCodeError
+		if errors.As(err, &coded) {
+			code = coded.code
+		} else if err != nil {
+			code = exitFailure
+		}
+		if code != tt.code {
+			t.Errorf("ci check from 100 to %v with --max-increase %s: got exit code %d (%v), want %d", tt.current, tt.limit, code, err, tt.code)
+		}
+	}
+	rootCmd.SetArgs(nil)
+}
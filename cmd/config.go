@@ -0,0 +1,537 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultAPIURL is the base URL of the Cloud Cents API
+const defaultAPIURL = "https://cloud-cents.onrender.com"
+
+// configSetting is a setting of the config file. A setting with a flag is the
+// default of that flag in every command that has it.
+type configSetting struct {
+	key    string   // dotted key in the config file, e.g. heatmap.palette
+	flag   string   // flag the setting is the default of, if any
+	kind   string   // string, number, numbers (a comma-separated list) or bool
+	values []string // accepted values of a string setting, if limited
+	def    string
+	usage  string
+}
+
+// configSettings are every setting of the config file. Each can also be set with
+// an environment variable, see envName.
+var configSettings = []configSetting{
+	{key: "profile", flag: "profile", kind: "string", def: defaultProfile, usage: "Active profile, see 'cloudcents profile list'"},
+	{key: "api_url", kind: "string", def: defaultAPIURL, usage: "Base URL of the Cloud Cents API"},
+	{key: "secret_store", kind: "string", values: secretStores, def: "auto", usage: "Where API keys are stored: auto (the OS keyring if there is one), keyring or file"},
+	{key: "catalog", flag: "catalog", kind: "string", usage: "Pricing catalog file"},
+	{key: "currency", flag: "currency", kind: "string", def: catalogCurrency, usage: "Currency to show prices and estimates in"},
+	{key: "output", flag: "output", kind: "string", values: outputFormats, def: "table", usage: "Output format"},
+	{key: "region", flag: "region", kind: "string", usage: "Region or geography prices and estimates are in"},
+	{key: "period", flag: "period", kind: "string", values: pricePeriods, usage: "Period prices are shown per"},
+	{key: "hours_per_month", flag: "hours-per-month", kind: "number", def: strconv.Itoa(defaultHoursPerMonth), usage: "Hours a month resources priced per hour run"},
+	{key: "pricing_model", flag: "pricing-model", kind: "string", values: pricingModels, def: "on-demand", usage: "Pricing model prices are compared in"},
+	{key: "heatmap.palette", flag: "palette", kind: "string", values: heatmapPaletteNames(), def: "default", usage: "Heatmap palette"},
+	{key: "heatmap.thresholds", flag: "heatmap-thresholds", kind: "numbers", def: "1.2,1.5", usage: "Multiples of the best price where the heatmap turns moderate and higher"},
+	{key: "heatmap.markers", kind: "bool", usage: "Add markers to the heatmap cells in any palette"},
+	{key: "heatmap.colors.best", kind: "string", usage: "Heatmap background of the best prices"},
+	{key: "heatmap.colors.lower", kind: "string", usage: "Heatmap background of lower prices"},
+	{key: "heatmap.colors.moderate", kind: "string", usage: "Heatmap background of moderate prices"},
+	{key: "heatmap.colors.higher", kind: "string", usage: "Heatmap background of higher prices"},
+}
+
+// fileSettings are the settings of the config file, loaded by loadSettings. The
+// settings of profiles are keyed by profiles.<name>.<key>.
+var fileSettings map[string]string
+
+// configCmd groups the commands that manage the config file
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Manage the cloudcents config file",
+	Long: `Manage the config file, config.yaml in the cloudcents config folder.
+
+Every setting can also be set with an environment variable named after it,
+e.g. CLOUDCENTS_OUTPUT for output or CLOUDCENTS_HEATMAP_PALETTE for
+heatmap.palette. Flags win over environment variables, which win over the
+settings of the active profile, which win over the rest of the config file,
+which wins over the defaults. With --profile, set writes to that profile.`,
+	// The config commands must work with a broken config file, to fix it
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return nil
+	},
+}
+
+// configListCmd lists every setting with its value and where the value comes from
+var configListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List every setting with its value and where it comes from",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := getOutputFormat(cmd)
+		if err != nil {
+			return err
+		}
+		if err := loadSettings(cmd); err != nil {
+			return err
+		}
+
+		type settingRecord struct {
+			Key    string `json:"key" yaml:"key"`
+			Value  string `json:"value" yaml:"value"`
+			Source string `json:"source" yaml:"source"`
+		}
+		var records []settingRecord
+		var rows [][]string
+		for _, s := range configSettings {
+			value, source := configLookup(s)
+			records = append(records, settingRecord{s.key, value, source})
+			rows = append(rows, []string{s.key, value, source})
+		}
+
+		switch format {
+		case "json":
+			return writeJSON(os.Stdout, records)
+		case "yaml":
+			return writeYAML(os.Stdout, records)
+		case "csv":
+			return writeCSV(os.Stdout, []string{"key", "value", "source"}, rows)
+		case "markdown":
+			return writeMarkdownTable(os.Stdout, []string{"key", "value", "source"}, rows)
+		}
+		fmt.Println(lineStyle.Render("Config file: " + configPath() + ", profile: " + currentProfile))
+		fmt.Println(headerStyle.Render(fmt.Sprintf("%-24s %-34s %s", "Setting", "Value", "Source")))
+		fmt.Println(lineStyle.Render(strings.Repeat("-", 90)))
+		for _, r := range records {
+			fmt.Printf("%-24s %-34s %s\n", r.Key, r.Value, lineStyle.Render(r.Source))
+		}
+		return nil
+	},
+}
+
+// configGetCmd prints the value of a setting
+var configGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "Print the value of a setting",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		s, err := findSetting(args[0])
+		if err != nil {
+			return err
+		}
+		if err := loadSettings(cmd); err != nil {
+			return err
+		}
+		value, _ := configLookup(s)
+		fmt.Println(value)
+		return nil
+	},
+}
+
+// configSetCmd writes a setting to the config file
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Write a setting to the config file, or remove it with an empty value",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		s, err := findSetting(args[0])
+		if err != nil {
+			return err
+		}
+		value, err := parseSetting(s, args[1])
+		if err != nil {
+			return withExitCode(exitUsage, err)
+		}
+
+		// With --profile the setting is written to that profile
+		path, where := strings.Split(s.key, "."), "'"+configPath()+"'"
+		if cmd.Flags().Changed("profile") {
+			profile, _ := cmd.Flags().GetString("profile")
+			if err := validateProfileName(profile); err != nil {
+				return withExitCode(exitUsage, err)
+			}
+			if s.key == "profile" {
+				return withExitCode(exitUsage, fmt.Errorf("the active profile can't be set in a profile, use 'cloudcents profile use'"))
+			}
+			path, where = append([]string{"profiles", profile}, path...), "profile "+profile
+		}
+
+		config, err := readConfigMap()
+		if err != nil {
+			return err
+		}
+		setConfigValue(config, path, value)
+		if err := writeConfigFile(config); err != nil {
+			return fmt.Errorf("writing config file '%s': %v", configPath(), err)
+		}
+
+		if value == nil {
+			displaySuccess(fmt.Sprintf("Removed %s from %s", s.key, where))
+		} else {
+			displaySuccess(fmt.Sprintf("Set %s to %s in %s", s.key, args[1], where))
+		}
+		if _, ok := os.LookupEnv(envName(s.key)); ok {
+			fmt.Println(infoStyle.Render(fmt.Sprintf("%s is set and takes precedence over the config file.", envName(s.key))))
+		}
+		return nil
+	},
+}
+
+// configEditCmd opens the config file in an editor
+var configEditCmd = &cobra.Command{
+	Use:   "edit",
+	Short: "Open the config file in $VISUAL or $EDITOR",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := configPath()
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				return err
+			}
+			if err := os.WriteFile(path, []byte(configTemplate()), 0644); err != nil {
+				return fmt.Errorf("writing config file '%s': %v", path, err)
+			}
+		}
+
+		editor := os.Getenv("VISUAL")
+		if editor == "" {
+			editor = os.Getenv("EDITOR")
+		}
+		if editor == "" {
+			editor = "vi"
+			if runtime.GOOS == "windows" {
+				editor = "notepad"
+			}
+		}
+		// The editor may come with arguments, e.g. "code --wait"
+		fields := strings.Fields(editor)
+		c := exec.Command(fields[0], append(fields[1:], path)...)
+		c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+		if err := c.Run(); err != nil {
+			return fmt.Errorf("running editor '%s': %v", editor, err)
+		}
+
+		if _, err := readConfigFile(); err != nil {
+			return err
+		}
+		displaySuccess(fmt.Sprintf("Saved '%s'", path))
+		return nil
+	},
+}
+
+// configPath returns the path of the config file
+func configPath() string {
+	return filepath.Join(getConfigDir(), "config.yaml")
+}
+
+// envName returns the environment variable of a setting, e.g. CLOUDCENTS_HEATMAP_PALETTE
+func envName(key string) string {
+	return "CLOUDCENTS_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// findSetting returns the setting with a key
+func findSetting(key string) (configSetting, error) {
+	for _, s := range configSettings {
+		if s.key == key {
+			return s, nil
+		}
+	}
+	return configSetting{}, withExitCode(exitUsage, fmt.Errorf("unknown setting '%s', see 'cloudcents config list' for every setting", key))
+}
+
+// loadSettings reads the config file and selects the active profile: --profile,
+// then CLOUDCENTS_PROFILE, then the profile setting of the config file
+func loadSettings(cmd *cobra.Command) error {
+	var err error
+	if fileSettings, err = readConfigFile(); err != nil {
+		return err
+	}
+	source := "--profile"
+	if f := cmd.Flags().Lookup("profile"); f != nil && f.Changed {
+		currentProfile = f.Value.String()
+	} else {
+		s, _ := findSetting("profile")
+		currentProfile, source = configLookup(s)
+	}
+	if err := validateProfileName(currentProfile); err != nil {
+		return withExitCode(exitUsage, fmt.Errorf("invalid profile from %s: %v", source, err))
+	}
+	return nil
+}
+
+// applyConfig makes the environment variables, the active profile and the config
+// file the defaults of the flags they set. Flags given on the command line are left
+// alone, and flags stay unchanged so commands can still tell they weren't given.
+// Every value set is validated, including those of settings without a flag.
+func applyConfig(cmd *cobra.Command) error {
+	if err := loadSettings(cmd); err != nil {
+		return err
+	}
+	for _, s := range configSettings {
+		value, source := configLookup(s)
+		if source == "default" {
+			continue
+		}
+		if _, err := parseSetting(s, value); err != nil {
+			return withExitCode(exitUsage, fmt.Errorf("%v, from %s", err, source))
+		}
+		f := cmd.Flags().Lookup(s.flag)
+		if s.flag == "" || f == nil || f.Changed {
+			continue
+		}
+		if err := f.Value.Set(value); err != nil {
+			return withExitCode(exitUsage, fmt.Errorf("invalid %s %q from %s: %v", s.key, value, source, err))
+		}
+	}
+	return nil
+}
+
+// configValue returns the value of a setting from its environment variable, the
+// active profile, the config file or its default
+func configValue(key string) string {
+	s, err := findSetting(key)
+	if err != nil {
+		return ""
+	}
+	value, _ := configLookup(s)
+	return value
+}
+
+// configLookup returns the value of a setting and where it comes from
+func configLookup(s configSetting) (string, string) {
+	if value, ok := os.LookupEnv(envName(s.key)); ok {
+		return value, envName(s.key)
+	}
+	if value, ok := fileSettings["profiles."+currentProfile+"."+s.key]; ok {
+		return value, "profile " + currentProfile
+	}
+	if value, ok := fileSettings[s.key]; ok {
+		return value, "config file"
+	}
+	return s.def, "default"
+}
+
+// parseSetting converts the text of a setting to the value written to the config
+// file. An empty text is nil, which removes the setting.
+func parseSetting(s configSetting, text string) (interface{}, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, nil
+	}
+	switch s.kind {
+	case "number":
+		v, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be a number, found %q", s.key, text)
+		}
+		return v, nil
+	case "numbers":
+		var values []float64
+		for _, field := range strings.Split(text, ",") {
+			v, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s must be a comma-separated list of numbers, found %q", s.key, text)
+			}
+			values = append(values, v)
+		}
+		return values, nil
+	case "bool":
+		v, err := strconv.ParseBool(text)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be true or false, found %q", s.key, text)
+		}
+		return v, nil
+	}
+	if len(s.values) > 0 && !contains(s.values, strings.ToLower(text)) {
+		return nil, fmt.Errorf("invalid %s '%s', expected one of %s", s.key, text, strings.Join(s.values, ", "))
+	}
+	return text, nil
+}
+
+// readConfigMap reads the config file as it is written. A missing config file is empty.
+func readConfigMap() (map[string]interface{}, error) {
+	config := map[string]interface{}{}
+	data, err := os.ReadFile(configPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return config, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading config file: %v", err)
+	}
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("invalid config file '%s': %v", configPath(), err)
+	}
+	if config == nil {
+		config = map[string]interface{}{}
+	}
+	return config, nil
+}
+
+// readConfigFile reads and validates the config file into the text of each
+// setting by key; lists are comma-separated
+func readConfigFile() (map[string]string, error) {
+	config, err := readConfigMap()
+	if err != nil {
+		return nil, err
+	}
+	settings := map[string]string{}
+	if err := flattenConfig("", config, settings); err != nil {
+		return nil, fmt.Errorf("invalid config file '%s': %v", configPath(), err)
+	}
+	return settings, nil
+}
+
+// flattenConfig adds the settings of a config section to settings, validating them
+func flattenConfig(prefix string, section map[string]interface{}, settings map[string]string) error {
+	keys := make([]string, 0, len(section))
+	for k := range section {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		key := prefix + k
+		if key == "profiles" {
+			if err := flattenProfiles(section[k], settings); err != nil {
+				return err
+			}
+			continue
+		}
+		if child, ok := section[k].(map[string]interface{}); ok {
+			if err := flattenConfig(key+".", child, settings); err != nil {
+				return err
+			}
+			continue
+		}
+		s, err := findSetting(key)
+		if err != nil {
+			return fmt.Errorf("unknown setting '%s'", key)
+		}
+
+		var text string
+		if list, ok := section[k].([]interface{}); ok {
+			var items []string
+			for _, item := range list {
+				items = append(items, fmt.Sprint(item))
+			}
+			text = strings.Join(items, ",")
+		} else if section[k] != nil {
+			text = fmt.Sprint(section[k])
+		}
+		if _, err := parseSetting(s, text); err != nil {
+			return err
+		}
+		settings[key] = text
+	}
+	return nil
+}
+
+// flattenProfiles adds the settings of the profiles section to settings as
+// profiles.<name>.<key>
+func flattenProfiles(section interface{}, settings map[string]string) error {
+	if section == nil {
+		return nil
+	}
+	profiles, ok := section.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("profiles must map profile names to their settings")
+	}
+	for name, value := range profiles {
+		if err := validateProfileName(name); err != nil {
+			return err
+		}
+		profile, ok := value.(map[string]interface{})
+		if !ok && value != nil {
+			return fmt.Errorf("profile '%s' must be a section of settings", name)
+		}
+		if _, ok := profile["profile"]; ok {
+			return fmt.Errorf("profile '%s' sets the active profile, which only the top of the file can", name)
+		}
+		if _, ok := profile["profiles"]; ok {
+			return fmt.Errorf("profile '%s' has profiles of its own", name)
+		}
+		profileSettings := map[string]string{}
+		if err := flattenConfig("", profile, profileSettings); err != nil {
+			return fmt.Errorf("profile '%s': %v", name, err)
+		}
+		for key, text := range profileSettings {
+			settings["profiles."+name+"."+key] = text
+		}
+	}
+	return nil
+}
+
+// setConfigValue sets the value of a dotted key in a config section, removing
+// sections left empty when the value is nil
+func setConfigValue(section map[string]interface{}, path []string, value interface{}) {
+	if len(path) == 1 {
+		if value == nil {
+			delete(section, path[0])
+		} else {
+			section[path[0]] = value
+		}
+		return
+	}
+	child, ok := section[path[0]].(map[string]interface{})
+	if !ok {
+		child = map[string]interface{}{}
+		section[path[0]] = child
+	}
+	setConfigValue(child, path[1:], value)
+	if len(child) == 0 {
+		delete(section, path[0])
+	}
+}
+
+// writeConfigFile writes the config file, creating its folder if needed
+func writeConfigFile(config map[string]interface{}) error {
+	if err := os.MkdirAll(filepath.Dir(configPath()), 0755); err != nil {
+		return err
+	}
+	var b bytes.Buffer
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(config); err != nil {
+		return err
+	}
+	return os.WriteFile(configPath(), b.Bytes(), 0644)
+}
+
+// configTemplate is a new config file listing every setting, commented out
+func configTemplate() string {
+	var b strings.Builder
+	b.WriteString("# cloudcents config file. Flags and CLOUDCENTS_* environment variables take\n")
+	b.WriteString("# precedence over it. Uncomment a setting to change it.\n")
+	for _, s := range configSettings {
+		fmt.Fprintf(&b, "\n# %s (%s)\n", s.usage, envName(s.key))
+		path := strings.Split(s.key, ".")
+		for i, part := range path[:len(path)-1] {
+			fmt.Fprintf(&b, "# %s%s:\n", strings.Repeat("  ", i), part)
+		}
+		fmt.Fprintf(&b, "# %s%s:", strings.Repeat("  ", len(path)-1), path[len(path)-1])
+		if s.def != "" {
+			b.WriteString(" " + s.def)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func init() {
+	configCmd.AddCommand(configListCmd)
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
+	configCmd.AddCommand(configEditCmd)
+	rootCmd.AddCommand(configCmd)
+}
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestApplyConfigValidatesEnvironment(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("APPDATA", t.TempDir())
+
+	tests := []struct {
+		env, value string
+		valid      bool
+	}{
+		{"CLOUDCENTS_SECRET_STORE", "bogus", false},
+		{"CLOUDCENTS_SECRET_STORE", "file", true},
+		{"CLOUDCENTS_HEATMAP_MARKERS", "maybe", false},
+		{"CLOUDCENTS_HOURS_PER_MONTH", "lots", false},
+		{"CLOUDCENTS_OUTPUT", "xml", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.env+"="+tt.value, func(t *testing.T) {
+			t.Setenv(tt.env, tt.value)
+			cmd := &cobra.Command{}
+			cmd.Flags().String("output", "table", "")
+
+			err := applyConfig(cmd)
+			if tt.valid {
+				if err != nil {
+					t.Errorf("applyConfig: %v", err)
+				}
+				return
+			}
+			var exitErr *exitCodeError
+			if !errors.As(err, &exitErr) || exitErr.code != exitUsage {
+				t.Errorf("got error %v, want one exiting with %d", err, exitUsage)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// catalogCurrency is the currency every catalog price is in
+const catalogCurrency = "USD"
+
+// displayCurrency, currencyRate and rateDate control which currency prices are shown
+// in: currencyRate converts catalog prices, from rates dated rateDate
+var (
+	displayCurrency         = catalogCurrency
+	currencyRate    float64 = 1
+	rateDate        string
+)
+
+// currencySymbols are the symbols of common currencies; others are shown by code
+var currencySymbols = map[string]string{
+	"USD": "$",
+	"EUR": "€",
+	"GBP": "£",
+	"INR": "₹",
+	"JPY": "¥",
+}
+
+// setCurrency validates --currency and loads the rate converting catalog prices to it
+func setCurrency(cmd *cobra.Command) error {
+	currency, _ := cmd.Flags().GetString("currency")
+	currency = strings.ToUpper(currency)
+	if currency == "" || currency == catalogCurrency {
+		displayCurrency, currencyRate, rateDate = catalogCurrency, 1, ""
+		return nil
+	}
+
+	rates, _, err := loadRates()
+	if err != nil {
+		return err
+	}
+	rate, err := rates.rate(catalogCurrency, currency)
+	if err != nil {
+		return withExitCode(exitUsage, err)
+	}
+	displayCurrency, currencyRate, rateDate = currency, rate, rates.Version
+	return nil
+}
+
+// currencySymbol returns the symbol prices are shown with, e.g. "€", or the
+// currency code followed by a space
+func currencySymbol() string {
+	if symbol, ok := currencySymbols[displayCurrency]; ok {
+		return symbol
+	}
+	return displayCurrency + " "
+}
+
+// formatMoney formats an amount in the display currency, e.g. "€12.50"
+func formatMoney(v float64) string {
+	if v < 0 {
+		return fmt.Sprintf("-%s%.2f", currencySymbol(), -v)
+	}
+	return fmt.Sprintf("%s%.2f", currencySymbol(), v)
+}
+
+// currencyNote describes the conversion applied to catalog prices for table output,
+// or returns an empty string for prices in the catalog currency
+func currencyNote() string {
+	if displayCurrency == catalogCurrency {
+		return ""
+	}
+	return fmt.Sprintf("Prices in %s at the exchange rate of %s (1 USD = %.4f %s)", displayCurrency, rateDate, currencyRate, displayCurrency)
+}
+
+// currencyHeader returns a table header label with the display currency, e.g. "AWS (€/mo)"
+func currencyHeader(label, per string) string {
+	return fmt.Sprintf("%s (%s%s)", label, strings.TrimSpace(currencySymbol()), per)
+}
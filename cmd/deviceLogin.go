@@ -0,0 +1,247 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// errLoginEnded is returned when the tokens of a device login can't be refreshed
+var errLoginEnded = errors.New("the device login has ended")
+
+// oauthClientID identifies cloudcents to the authorization server
+const oauthClientID = "cloudcents-cli"
+
+// deviceCodeLifetime is how long a device login waits for approval when the
+// authorization server doesn't say when its code expires
+const deviceCodeLifetime = 15 * time.Minute
+
+// tokenRefreshMargin is how long before it expires an access token is refreshed
+const tokenRefreshMargin = time.Minute
+
+// pollWait waits between polls of the token endpoint; tests replace it so they
+// don't wait
+var pollWait = time.Sleep
+
+// deviceCode is the response of the device authorization endpoint (RFC 8628)
+type deviceCode struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"` // with the user code filled in
+	ExpiresIn               int    `json:"expires_in"`                // seconds
+	Interval                int    `json:"interval"`                  // seconds between polls
+}
+
+// oauthToken is the response of the token endpoint
+type oauthToken struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"` // seconds, 0 when the token doesn't expire
+}
+
+// storedTokens are the tokens of a device login as kept in the secret store, in
+// place of an API key
+type storedTokens struct {
+	Type         string    `json:"type"` // always "oauth", to tell them from an API key
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	ExpiresAt    time.Time `json:"expires_at,omitempty"`
+}
+
+// oauthError is an OAuth error response (RFC 6749, section 5.2)
+type oauthError struct {
+	Code        string `json:"error"` // e.g. authorization_pending or invalid_grant
+	Description string `json:"error_description"`
+}
+
+func (e *oauthError) Error() string {
+	if e.Description != "" {
+		return e.Description
+	}
+	return strings.ReplaceAll(e.Code, "_", " ")
+}
+
+// oauthEndpoint returns the URL of an OAuth endpoint of the API
+func oauthEndpoint(path string) string {
+	return strings.TrimRight(configValue("api_url"), "/") + "/oauth/" + path
+}
+
+// postForm posts a form to an OAuth endpoint and decodes the JSON response into v.
+// An OAuth error response is returned as an *oauthError.
+func postForm(endpoint string, form url.Values, v interface{}) error {
+	client := &http.Client{Timeout: apiTimeout}
+	resp, err := client.PostForm(endpoint, form)
+	if err != nil {
+		return fmt.Errorf("could not reach the Cloud Cents API: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("reading the response of %s: %v", endpoint, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var e oauthError
+		if json.Unmarshal(body, &e) == nil && e.Code != "" {
+			return &e
+		}
+		return fmt.Errorf("the Cloud Cents API answered %s", resp.Status)
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("unexpected response from %s: %v", endpoint, err)
+	}
+	return nil
+}
+
+// oauthErrorCode returns the OAuth error code of err, e.g. authorization_pending
+func oauthErrorCode(err error) string {
+	var e *oauthError
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return ""
+}
+
+// deviceLogin runs the OAuth device authorization grant: it shows a code to enter in
+// the browser, waits until the user has approved it and returns the tokens
+func deviceLogin(browser bool) (*storedTokens, error) {
+	var code deviceCode
+	err := postForm(oauthEndpoint("device/code"), url.Values{"client_id": {oauthClientID}}, &code)
+	if err != nil {
+		return nil, fmt.Errorf("starting the device login: %v", err)
+	}
+	if code.DeviceCode == "" || code.UserCode == "" {
+		return nil, fmt.Errorf("starting the device login: the Cloud Cents API sent no device code")
+	}
+
+	link := code.VerificationURIComplete
+	if link == "" {
+		link = code.VerificationURI
+	}
+	fmt.Printf("Open %s and enter the code:\n\n    %s\n\n", code.VerificationURI, headerStyle.Render(code.UserCode))
+	if browser {
+		if err := openBrowser(link); err != nil {
+			fmt.Println(infoStyle.Render(fmt.Sprintf("Could not open a browser (%v), open the link yourself.", err)))
+		}
+	}
+	fmt.Fprintln(os.Stderr, infoStyle.Render("Waiting for you to approve the login..."))
+
+	interval := time.Duration(code.Interval) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	deadline := code.deadline(time.Now())
+	form := url.Values{
+		"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+		"device_code": {code.DeviceCode},
+		"client_id":   {oauthClientID},
+	}
+	for {
+		pollWait(interval)
+		var token oauthToken
+		err := postForm(oauthEndpoint("token"), form, &token)
+		switch oauthErrorCode(err) {
+		case "":
+			if err != nil {
+				return nil, err
+			}
+			if token.AccessToken == "" {
+				return nil, fmt.Errorf("logging in: the Cloud Cents API sent no access token")
+			}
+			return newStoredTokens(token), nil
+		case "authorization_pending":
+		case "slow_down":
+			interval += 5 * time.Second
+		case "access_denied":
+			return nil, fmt.Errorf("%w: the login was denied in the browser", errKeyInvalid)
+		case "expired_token":
+			return nil, fmt.Errorf("the code expired before the login was approved, run 'cloudcents login --device' again")
+		default:
+			return nil, fmt.Errorf("logging in: %v", err)
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("the code expired before the login was approved, run 'cloudcents login --device' again")
+		}
+	}
+}
+
+// deadline returns when a device code requested at now expires, after
+// deviceCodeLifetime when expires_in is missing
+func (c deviceCode) deadline(now time.Time) time.Time {
+	if c.ExpiresIn <= 0 {
+		return now.Add(deviceCodeLifetime)
+	}
+	return now.Add(time.Duration(c.ExpiresIn) * time.Second)
+}
+
+// newStoredTokens returns the tokens of a token response to keep
+func newStoredTokens(token oauthToken) *storedTokens {
+	t := &storedTokens{Type: "oauth", AccessToken: token.AccessToken, RefreshToken: token.RefreshToken}
+	if token.ExpiresIn > 0 {
+		t.ExpiresAt = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second).UTC()
+	}
+	return t
+}
+
+// encode returns the tokens as kept in the secret store
+func (t *storedTokens) encode() string {
+	data, _ := json.Marshal(t)
+	return string(data)
+}
+
+// parseStoredTokens returns the tokens of a device login kept in the secret store,
+// or false when the secret is an API key
+func parseStoredTokens(secret string) (*storedTokens, bool) {
+	if !strings.HasPrefix(secret, "{") {
+		return nil, false
+	}
+	var t storedTokens
+	if err := json.Unmarshal([]byte(secret), &t); err != nil || t.Type != "oauth" {
+		return nil, false
+	}
+	return &t, true
+}
+
+// accessToken returns the access token of a device login, refreshing it first and
+// storing the new tokens when it is about to expire
+func accessToken(store secretStore, t *storedTokens) (string, error) {
+	if t.ExpiresAt.IsZero() || time.Until(t.ExpiresAt) > tokenRefreshMargin {
+		return t.AccessToken, nil
+	}
+	if t.RefreshToken == "" {
+		return "", errLoginEnded
+	}
+
+	var token oauthToken
+	err := postForm(oauthEndpoint("token"), url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {t.RefreshToken},
+		"client_id":     {oauthClientID},
+	}, &token)
+	if oauthErrorCode(err) == "invalid_grant" {
+		return "", errLoginEnded
+	}
+	if err != nil {
+		return "", fmt.Errorf("refreshing the login: %v", err)
+	}
+	if token.AccessToken == "" {
+		return "", fmt.Errorf("refreshing the login: the Cloud Cents API sent no access token")
+	}
+	refreshed := newStoredTokens(token)
+	if refreshed.RefreshToken == "" {
+		// The refresh token is kept when the server doesn't rotate it
+		refreshed.RefreshToken = t.RefreshToken
+	}
+	if err := store.set(currentProfile, refreshed.encode()); err != nil {
+		return "", fmt.Errorf("storing the refreshed login in the %s: %v", store.name(), err)
+	}
+	return refreshed.AccessToken, nil
+}
@@ -0,0 +1,248 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDeviceCodeDeadline(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		expiresIn int
+		want      time.Duration
+	}{
+		{600, 10 * time.Minute},
+		{0, deviceCodeLifetime},
+		{-1, deviceCodeLifetime},
+	}
+	for _, tt := range tests {
+		if got := (deviceCode{ExpiresIn: tt.expiresIn}).deadline(now); !got.Equal(now.Add(tt.want)) {
+			t.Errorf("deadline with expires_in %d = %v, want %v", tt.expiresIn, got, now.Add(tt.want))
+		}
+	}
+}
+
+// tokenResponse is an answer of the stand-in token endpoint
+type tokenResponse struct {
+	status int
+	body   string
+}
+
+// deviceServer stands in for the OAuth endpoints of the Cloud Cents API: the device
+// endpoint answers with deviceBody and the token endpoint with each response in
+// turn. It returns the forms posted to the token endpoint.
+func deviceServer(t *testing.T, deviceBody string, responses ...tokenResponse) *[]url.Values {
+	t.Helper()
+	var forms []url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil || r.Form.Get("client_id") != oauthClientID {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error":"invalid_client"}`)
+			return
+		}
+		switch r.URL.Path {
+		case "/oauth/device/code":
+			fmt.Fprint(w, deviceBody)
+		case "/oauth/token":
+			forms = append(forms, r.Form)
+			if len(forms) > len(responses) {
+				t.Errorf("token endpoint polled %d times, expected %d", len(forms), len(responses))
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			resp := responses[len(forms)-1]
+			w.WriteHeader(resp.status)
+			fmt.Fprint(w, resp.body)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	t.Setenv("CLOUDCENTS_API_URL", srv.URL)
+	return &forms
+}
+
+// recordPollWaits replaces the wait between polls, recording each instead
+func recordPollWaits(t *testing.T) *[]time.Duration {
+	t.Helper()
+	var waits []time.Duration
+	saved := pollWait
+	pollWait = func(d time.Duration) { waits = append(waits, d) }
+	t.Cleanup(func() { pollWait = saved })
+	return &waits
+}
+
+// discardPrompt keeps the code and link deviceLogin prints out of the test output
+func discardPrompt(t *testing.T) {
+	t.Helper()
+	stdout, stderr := os.Stdout, os.Stderr
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stdout, os.Stderr = devNull, devNull
+	t.Cleanup(func() {
+		os.Stdout, os.Stderr = stdout, stderr
+		devNull.Close()
+	})
+}
+
+const testDeviceCode = `{"device_code":"dev-123","user_code":"ABCD-EFGH","verification_uri":"https://example.com/device","expires_in":600,"interval":2}`
+
+func TestDeviceLogin(t *testing.T) {
+	pending := tokenResponse{http.StatusBadRequest, `{"error":"authorization_pending"}`}
+	slowDown := tokenResponse{http.StatusBadRequest, `{"error":"slow_down"}`}
+	approved := tokenResponse{http.StatusOK, `{"access_token":"at-1","refresh_token":"rt-1","token_type":"Bearer","expires_in":3600}`}
+	forms := deviceServer(t, testDeviceCode, pending, slowDown, pending, approved)
+	waits := recordPollWaits(t)
+	discardPrompt(t)
+
+	tokens, err := deviceLogin(false)
+	if err != nil {
+		t.Fatalf("deviceLogin: %v", err)
+	}
+	if tokens.Type != "oauth" || tokens.AccessToken != "at-1" || tokens.RefreshToken != "rt-1" {
+		t.Errorf("got tokens %+v", tokens)
+	}
+	if until := time.Until(tokens.ExpiresAt); until < 59*time.Minute || until > time.Hour {
+		t.Errorf("got tokens expiring in %v, want an hour", until)
+	}
+	// slow_down adds 5 seconds to every later poll
+	want := []time.Duration{2 * time.Second, 2 * time.Second, 7 * time.Second, 7 * time.Second}
+	if !reflect.DeepEqual(*waits, want) {
+		t.Errorf("waited %v between polls, want %v", *waits, want)
+	}
+	for _, form := range *forms {
+		if form.Get("grant_type") != "urn:ietf:params:oauth:grant-type:device_code" || form.Get("device_code") != "dev-123" {
+			t.Errorf("polled with form %v", form)
+		}
+	}
+}
+
+func TestDeviceLoginFailures(t *testing.T) {
+	tests := []struct {
+		name       string
+		deviceBody string
+		responses  []tokenResponse
+		err        string
+		reason     error
+	}{
+		{"denied", testDeviceCode, []tokenResponse{{http.StatusBadRequest, `{"error":"access_denied"}`}},
+			"the login was denied in the browser", errKeyInvalid},
+		{"expired code", testDeviceCode, []tokenResponse{{http.StatusBadRequest, `{"error":"authorization_pending"}`}, {http.StatusBadRequest, `{"error":"expired_token"}`}},
+			"the code expired before the login was approved", nil},
+		{"other OAuth error", testDeviceCode, []tokenResponse{{http.StatusBadRequest, `{"error":"invalid_grant","error_description":"the device code is unknown"}`}},
+			"logging in: the device code is unknown", nil},
+		{"no device code", `{"user_code":"ABCD-EFGH","verification_uri":"https://example.com/device"}`, nil,
+			"the Cloud Cents API sent no device code", nil},
+		{"no access token", testDeviceCode, []tokenResponse{{http.StatusOK, `{"token_type":"Bearer"}`}},
+			"the Cloud Cents API sent no access token", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deviceServer(t, tt.deviceBody, tt.responses...)
+			recordPollWaits(t)
+			discardPrompt(t)
+
+			tokens, err := deviceLogin(false)
+			if err == nil {
+				t.Fatalf("got tokens %+v, want an error", tokens)
+			}
+			if !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("got error %q, want %q", err, tt.err)
+			}
+			if tt.reason != nil && !errors.Is(err, tt.reason) {
+				t.Errorf("got error %v, want %v", err, tt.reason)
+			}
+		})
+	}
+}
+
+// memoryStore is a secret store in memory
+type memoryStore map[string]string
+
+func (s memoryStore) name() string { return "memory" }
+func (s memoryStore) get(profile string) (string, error) {
+	secret, ok := s[profile]
+	if !ok {
+		return "", errNoAPIKey
+	}
+	return secret, nil
+}
+func (s memoryStore) set(profile, secret string) error { s[profile] = secret; return nil }
+func (s memoryStore) delete(profile string) error      { delete(s, profile); return nil }
+func (s memoryStore) has(profile string) bool          { _, ok := s[profile]; return ok }
+
+func TestAccessTokenRefresh(t *testing.T) {
+	expiring := func() *storedTokens {
+		return &storedTokens{Type: "oauth", AccessToken: "at-old", RefreshToken: "rt-old", ExpiresAt: time.Now().Add(30 * time.Second)}
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		store := memoryStore{}
+		token, err := accessToken(store, &storedTokens{Type: "oauth", AccessToken: "at-old", ExpiresAt: time.Now().Add(time.Hour)})
+		if err != nil || token != "at-old" || len(store) != 0 {
+			t.Errorf("got %q, %v and store %v; want the stored token without a refresh", token, err, store)
+		}
+	})
+
+	t.Run("refreshed", func(t *testing.T) {
+		forms := deviceServer(t, testDeviceCode, tokenResponse{http.StatusOK, `{"access_token":"at-new","expires_in":3600}`})
+		store := memoryStore{}
+		token, err := accessToken(store, expiring())
+		if err != nil || token != "at-new" {
+			t.Fatalf("got %q, %v; want at-new", token, err)
+		}
+		if form := (*forms)[0]; form.Get("grant_type") != "refresh_token" || form.Get("refresh_token") != "rt-old" {
+			t.Errorf("refreshed with form %v", form)
+		}
+		// The refresh token is kept when the server doesn't rotate it
+		stored, ok := parseStoredTokens(store[currentProfile])
+		if !ok || stored.AccessToken != "at-new" || stored.RefreshToken != "rt-old" || stored.ExpiresAt.IsZero() {
+			t.Errorf("stored %q", store[currentProfile])
+		}
+	})
+
+	tests := []struct {
+		name     string
+		response tokenResponse
+		err      string
+		reason   error
+	}{
+		{"revoked refresh token", tokenResponse{http.StatusBadRequest, `{"error":"invalid_grant"}`}, "the device login has ended", errLoginEnded},
+		{"no access token", tokenResponse{http.StatusOK, `{"refresh_token":"rt-new"}`}, "the Cloud Cents API sent no access token", nil},
+		{"server error", tokenResponse{http.StatusBadGateway, "Bad Gateway"}, "refreshing the login: the Cloud Cents API answered 502 Bad Gateway", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deviceServer(t, testDeviceCode, tt.response)
+			store := memoryStore{}
+			token, err := accessToken(store, expiring())
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("got %q, %v; want error %q", token, err, tt.err)
+			}
+			if tt.reason != nil && !errors.Is(err, tt.reason) {
+				t.Errorf("got error %v, want %v", err, tt.reason)
+			}
+			if len(store) != 0 {
+				t.Errorf("stored %v after a failed refresh", store)
+			}
+		})
+	}
+
+	t.Run("no refresh token", func(t *testing.T) {
+		tokens := expiring()
+		tokens.RefreshToken = ""
+		if _, err := accessToken(memoryStore{}, tokens); !errors.Is(err, errLoginEnded) {
+			t.Errorf("got error %v, want %v", err, errLoginEnded)
+		}
+	})
+}
@@ -0,0 +1,464 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// estimateCmd prices a workload spec on every provider in the catalog
+var estimateCmd = &cobra.Command{
+	Use:   "estimate -f workload.yaml",
+	Short: "Estimate the monthly cost of a workload on every provider",
+	Long: `Estimate the monthly cost of a workload described in a YAML file on every
+provider in the pricing catalog, using the same catalog as 'prices':
+
+  name: web-tier
+  region: us-east          # optional region or geography
+  hours_per_month: 730     # optional, for resources priced per hour
+  resources:
+    - name: app servers
+      service: compute
+      size: medium
+      quantity: 12         # instances
+    - name: data
+      service: storage
+      size: medium
+      quantity: 4TB        # GB for resources priced per GB
+    - name: egress
+      service: network
+      size: internet-egress
+      quantity: 2TB`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, _ := cmd.Flags().GetString("file")
+		spec, err := readWorkloadSpec(path)
+		if err != nil {
+			return fmt.Errorf("reading workload spec: %v", err)
+		}
+		if cmd.Flags().Changed("region") || spec.Region == "" {
+			spec.Region, _ = cmd.Flags().GetString("region")
+		}
+		if cmd.Flags().Changed("hours-per-month") || spec.HoursPerMonth == nil {
+			hours, _ := cmd.Flags().GetFloat64("hours-per-month")
+			if err := checkHoursPerMonth(hours); err != nil {
+				return withExitCode(exitUsage, err)
+			}
+			spec.HoursPerMonth = &hours
+		}
+
+		format, err := getOutputFormat(cmd)
+		if err != nil {
+			return err
+		}
+		if err := loadPricingData(cmd); err != nil {
+			return err
+		}
+
+		est, err := estimateWorkload(spec)
+		if err != nil {
+			return err
+		}
+		if err := writeEstimate(os.Stdout, format, est); err != nil {
+			return fmt.Errorf("writing estimate: %v", err)
+		}
+		return nil
+	},
+}
+
+// workloadSpec is the YAML description of a workload read by estimate -f
+type workloadSpec struct {
+	Name          string             `yaml:"name"`
+	Region        string             `yaml:"region"`
+	HoursPerMonth *float64           `yaml:"hours_per_month"`
+	Resources     []workloadResource `yaml:"resources"`
+}
+
+// workloadResource is a line item of a workload spec
+type workloadResource struct {
+	Name     string   `yaml:"name"`
+	Service  string   `yaml:"service"`
+	Size     string   `yaml:"size"`
+	Quantity quantity `yaml:"quantity"`
+}
+
+// quantity is a resource quantity: a plain number, or an amount of data such as
+// "4TB" or "500 GiB" that is converted to GB
+type quantity float64
+
+// quantityUnits are the data units a quantity can be given in, as multiples of the GB
+// storage is priced in. TB and PB are decimal, TiB and PiB binary.
+var quantityUnits = map[string]float64{
+	"":    1,
+	"gb":  1,
+	"gib": 1,
+	"tb":  1000,
+	"tib": 1024,
+	"pb":  1000 * 1000,
+	"pib": 1024 * 1024,
+}
+
+// quantityPattern matches a number followed by an optional data unit
+var quantityPattern = regexp.MustCompile(`^([0-9]*\.?[0-9]+)\s*([A-Za-z]*)$`)
+
+// UnmarshalYAML parses a quantity from a number or a string with a data unit
+func (q *quantity) UnmarshalYAML(value *yaml.Node) error {
+	m := quantityPattern.FindStringSubmatch(strings.TrimSpace(value.Value))
+	if value.Kind != yaml.ScalarNode || m == nil {
+		return fmt.Errorf("line %d: invalid quantity %q", value.Line, value.Value)
+	}
+	factor, ok := quantityUnits[strings.ToLower(m[2])]
+	if !ok {
+		return fmt.Errorf("line %d: unknown unit %q in quantity, expected GB, TB, PB, GiB, TiB or PiB", value.Line, m[2])
+	}
+	n, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return fmt.Errorf("line %d: invalid quantity %q", value.Line, value.Value)
+	}
+	*q = quantity(n * factor)
+	return nil
+}
+
+// readWorkloadSpec reads and checks a workload spec, rejecting unknown fields
+func readWorkloadSpec(path string) (workloadSpec, error) {
+	var spec workloadSpec
+	if path == "" {
+		return spec, fmt.Errorf("no workload spec given, use -f workload.yaml")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return spec, err
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&spec); err != nil && err != io.EOF {
+		return spec, fmt.Errorf("%s: %v", path, err)
+	}
+	if len(spec.Resources) == 0 {
+		return spec, fmt.Errorf("%s: no resources to estimate", path)
+	}
+	if spec.HoursPerMonth != nil {
+		if err := checkHoursPerMonth(*spec.HoursPerMonth); err != nil {
+			return spec, fmt.Errorf("%s: %v", path, err)
+		}
+	}
+	for i, r := range spec.Resources {
+		if r.Service == "" || r.Size == "" {
+			return spec, fmt.Errorf("%s: resource %d needs a service and a size", path, i+1)
+		}
+		if r.Quantity < 0 {
+			return spec, fmt.Errorf("%s: resource %d has a negative quantity", path, i+1)
+		}
+		if r.Name == "" {
+			spec.Resources[i].Name = r.Service + " " + r.Size
+		}
+	}
+	return spec, nil
+}
+
+// estimate is the priced result of a workload on every provider
+type estimate struct {
+	Name          string             `json:"name,omitempty" yaml:"name,omitempty"`
+	Region        string             `json:"region,omitempty" yaml:"region,omitempty"`
+	HoursPerMonth float64            `json:"hours_per_month" yaml:"hours_per_month"`
+	Providers     []string           `json:"providers" yaml:"providers"`
+	Items         []estimateItem     `json:"items" yaml:"items"`
+	Totals        map[string]float64 `json:"monthly_totals" yaml:"monthly_totals"`
+	Incomplete    []string           `json:"incomplete,omitempty" yaml:"incomplete,omitempty"`
+	Cheapest      string             `json:"cheapest,omitempty" yaml:"cheapest,omitempty"`
+	Currency      string             `json:"currency" yaml:"currency"`
+	RateDate      string             `json:"rate_date,omitempty" yaml:"rate_date,omitempty"`
+
+	// HomeTotal is the monthly cost with every item on the provider it is defined
+	// for, when items have one (e.g. resources of a Terraform plan). It is nil
+	// while any item is unpriced, rather than a total that leaves items out.
+	HomeTotal *float64 `json:"home_total,omitempty" yaml:"home_total,omitempty"`
+	Unpriced  []string `json:"unpriced,omitempty" yaml:"unpriced,omitempty"`
+}
+
+// estimateItem is a priced line item of an estimate
+type estimateItem struct {
+	Name     string             `json:"name" yaml:"name"`
+	Provider string             `json:"provider,omitempty" yaml:"provider,omitempty"`
+	Region   string             `json:"region,omitempty" yaml:"region,omitempty"`
+	Service  string             `json:"service" yaml:"service"`
+	Size     string             `json:"size" yaml:"size"`
+	Quantity float64            `json:"quantity" yaml:"quantity"`
+	Unit     string             `json:"unit,omitempty" yaml:"unit,omitempty"`
+	SKUs     map[string]string  `json:"skus,omitempty" yaml:"skus,omitempty"`
+	Costs    map[string]float64 `json:"monthly_cost" yaml:"monthly_cost"`
+	Cheapest string             `json:"cheapest,omitempty" yaml:"cheapest,omitempty"`
+}
+
+// estimateLine is a line item to price, with the SKU each provider would use for it
+type estimateLine struct {
+	item   estimateItem
+	skuFor func(provider string) (SKU, bool)
+}
+
+// estimateWorkload prices every resource of a workload spec on every provider
+func estimateWorkload(spec workloadSpec) (estimate, error) {
+	geo := ""
+	if spec.Region != "" {
+		geos, err := resolveRegions([]string{spec.Region}, prices.regions())
+		if err != nil {
+			return estimate{}, err
+		}
+		geo = geos[0]
+	}
+
+	var lines []estimateLine
+	for _, r := range spec.Resources {
+		r := r
+		lines = append(lines, estimateLine{
+			item: estimateItem{Name: r.Name, Service: r.Service, Size: r.Size, Quantity: float64(r.Quantity)},
+			skuFor: func(provider string) (SKU, bool) {
+				return prices.lookupSKU(provider, r.Service, r.Size, geo)
+			},
+		})
+	}
+
+	hours := float64(defaultHoursPerMonth)
+	if spec.HoursPerMonth != nil {
+		hours = *spec.HoursPerMonth
+	}
+	est := buildEstimate(spec.Name, geo, hours, lines)
+	for _, item := range est.Items {
+		if len(item.Costs) == 0 {
+			return est, fmt.Errorf("resource %q: no provider offers %s %s", item.Name, item.Service, item.Size)
+		}
+	}
+	return est, nil
+}
+
+// buildEstimate prices every line on every provider. Totals only include providers
+// that can price every line; the others are listed as incomplete. Lines their own
+// provider can't price are listed as unpriced.
+func buildEstimate(name, geo string, hours float64, lines []estimateLine) estimate {
+	est := estimate{Name: name, Region: geo, HoursPerMonth: hours, Totals: map[string]float64{},
+		Currency: displayCurrency, RateDate: rateDate}
+	for _, p := range prices.Providers {
+		est.Providers = append(est.Providers, p.Name)
+	}
+
+	missing := map[string]bool{}
+	homeItems, homeTotal := false, 0.0
+	for _, line := range lines {
+		item := line.item
+		item.Costs = map[string]float64{}
+		item.SKUs = map[string]string{}
+		for _, p := range est.Providers {
+			sku, ok := line.skuFor(p)
+			if !ok {
+				missing[p] = true
+				continue
+			}
+			if item.Unit == "" {
+				item.Unit = sku.Unit
+			}
+			if sku.ID != "" {
+				item.SKUs[p] = sku.ID
+			}
+			item.Costs[p] = roundPrice(monthlyCost(sku, item.Quantity, hours))
+		}
+		item.Cheapest = cheapestProvider(est.Providers, item.Costs)
+		if item.Provider != "" {
+			homeItems = true
+			if cost, ok := item.Costs[item.Provider]; ok {
+				homeTotal += cost
+			} else {
+				est.Unpriced = append(est.Unpriced, fmt.Sprintf("%s: no %s price on %s", item.Name,
+					strings.TrimSpace(item.Service+" "+item.Size), providerLabel(item.Provider)))
+			}
+		}
+		est.Items = append(est.Items, item)
+	}
+	if homeItems && len(est.Unpriced) == 0 {
+		total := roundPrice(homeTotal)
+		est.HomeTotal = &total
+	}
+
+	for _, p := range est.Providers {
+		if missing[p] {
+			est.Incomplete = append(est.Incomplete, p)
+			continue
+		}
+		total := 0.0
+		for _, item := range est.Items {
+			total += item.Costs[p]
+		}
+		est.Totals[p] = roundPrice(total)
+	}
+	est.Cheapest = cheapestProvider(est.Providers, est.Totals)
+	return est
+}
+
+// monthlyCost returns the monthly cost of a quantity of a SKU in the display currency.
+// Hourly prices are charged for the given hours, other time-based prices for a full
+// month; usage-based prices treat the quantity as monthly usage.
+func monthlyCost(sku SKU, qty, hours float64) float64 {
+	times, _, ok := perMonth(sku.Unit)
+	if !ok {
+		times = 1
+	}
+	if sku.Unit == "hour" {
+		times = hours
+	}
+	return sku.Price * currencyRate * times * qty
+}
+
+// cheapestProvider returns the provider with the lowest cost, in provider order on ties
+func cheapestProvider(providers []string, costs map[string]float64) string {
+	cheapest := ""
+	for _, p := range providers {
+		cost, ok := costs[p]
+		if ok && (cheapest == "" || cost < costs[cheapest]) {
+			cheapest = p
+		}
+	}
+	return cheapest
+}
+
+// writeEstimate writes an estimate as a styled table or in a machine-readable format
+func writeEstimate(w io.Writer, format string, est estimate) error {
+	switch format {
+	case "json":
+		return writeJSON(w, est)
+	case "yaml":
+		return writeYAML(w, est)
+	}
+
+	header := []string{"Item", "Service", "Size", "Quantity"}
+	for _, p := range est.Providers {
+		header = append(header, currencyHeader(providerLabel(p), "/mo"))
+	}
+	var rows [][]string
+	for _, item := range est.Items {
+		row := []string{item.Name, item.Service, item.Size, formatQuantity(item.Quantity)}
+		for _, p := range est.Providers {
+			cost, ok := item.Costs[p]
+			if !ok {
+				row = append(row, "")
+				continue
+			}
+			row = append(row, fmt.Sprintf("%.2f", cost))
+		}
+		rows = append(rows, row)
+	}
+	total := []string{"Total", "", "", ""}
+	for _, p := range est.Providers {
+		cost, ok := est.Totals[p]
+		if !ok {
+			total = append(total, "")
+			continue
+		}
+		total = append(total, fmt.Sprintf("%.2f", cost))
+	}
+
+	switch format {
+	case "csv":
+		// Every row carries the currency, so the file can be read on its own
+		header = append(header, "currency", "rate_date")
+		rows = append(rows, total)
+		for i := range rows {
+			rows[i] = append(rows[i], est.Currency, est.RateDate)
+		}
+		return writeCSV(w, header, rows)
+	case "markdown":
+		for i, p := range est.Providers {
+			if p == est.Cheapest {
+				total[4+i] = "**" + total[4+i] + "**"
+			}
+		}
+		return writeMarkdownTable(w, header, append(rows, total))
+	}
+	printEstimateTable(w, est)
+	return nil
+}
+
+// printEstimateTable prints an estimate as a table, coloring every line item and
+// the totals with the same heatmap as the pricing table
+func printEstimateTable(w io.Writer, est estimate) {
+	title := "Monthly cost estimate"
+	if est.Name != "" {
+		title += " for " + est.Name
+	}
+	if est.Region != "" {
+		title += " in " + est.Region
+	}
+	fmt.Fprintln(w, headerStyle.Render(title))
+	fmt.Fprintln(w, lineStyle.Render("Catalog: "+catalogSource))
+	if note := currencyNote(); note != "" {
+		fmt.Fprintln(w, lineStyle.Render(note))
+	}
+	fmt.Fprintln(w)
+
+	header := fmt.Sprintf("%-20s %-15s %-15s %10s", "Item", "Service", "Size", "Quantity")
+	for _, p := range est.Providers {
+		header += fmt.Sprintf(" %13s", currencyHeader(providerLabel(p), "/mo"))
+	}
+	width := 63 + 14*len(est.Providers)
+	fmt.Fprintln(w, headerStyle.Render(header))
+	fmt.Fprintln(w, lineStyle.Render(strings.Repeat("-", width)))
+
+	costCells := func(costs map[string]float64) string {
+		best := costs[cheapestProvider(est.Providers, costs)]
+		cells := ""
+		for _, p := range est.Providers {
+			cost, ok := costs[p]
+			if !ok {
+				cells += " " + cellStyle.Render(fmt.Sprintf("%9s", "-"))
+				continue
+			}
+			cells += " " + heatmapCell(fmt.Sprintf("%9.2f", cost), cost, best)
+		}
+		return cells
+	}
+
+	for _, item := range est.Items {
+		row := fmt.Sprintf("%-20s %-15s %-15s %10s", truncate(item.Name, 20), item.Service, item.Size, formatQuantity(item.Quantity))
+		fmt.Fprintln(w, row+costCells(item.Costs))
+	}
+	fmt.Fprintln(w, lineStyle.Render(strings.Repeat("-", width)))
+	fmt.Fprintln(w, headerStyle.Render(fmt.Sprintf("%-20s %-15s %-15s %10s", "Total", "", "", ""))+costCells(est.Totals))
+
+	if est.Cheapest != "" {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, successStyle.Render(fmt.Sprintf("Cheapest: %s at %s/month", providerLabel(est.Cheapest), formatMoney(est.Totals[est.Cheapest]))))
+	}
+	for _, p := range est.Incomplete {
+		fmt.Fprintln(w, infoStyle.Render(fmt.Sprintf("%s has no total because it doesn't offer every resource", providerLabel(p))))
+	}
+}
+
+// providerLabel returns the display name of a provider in the loaded catalog
+func providerLabel(name string) string {
+	if p := prices.provider(name); p != nil {
+		return p.DisplayName()
+	}
+	return strings.ToUpper(name)
+}
+
+// truncate shortens s to at most n runes, marking the cut with an ellipsis
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
+
+func init() {
+	estimateCmd.Flags().StringP("file", "f", "", "Workload spec YAML file")
+	estimateCmd.Flags().String("region", "", "Region or geography to price the workload in (overrides the spec)")
+	estimateCmd.Flags().Float64("hours-per-month", defaultHoursPerMonth, "Hours a month resources priced per hour run (overrides the spec)")
+	rootCmd.AddCommand(estimateCmd)
+}
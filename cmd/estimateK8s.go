@@ -0,0 +1,521 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// estimateK8sCmd estimates the monthly cost of Kubernetes workloads from their manifests
+var estimateK8sCmd = &cobra.Command{
+	Use:   "k8s -f manifests/",
+	Short: "Estimate the monthly cost of Kubernetes manifests on every provider",
+	Long: `Estimate the monthly cost of Kubernetes manifests, or of Helm charts rendered
+with 'helm template' (use -f - to read them from stdin).
+
+The CPU and memory requests of Deployments, StatefulSets and DaemonSets are
+multiplied by their replicas and priced as a share of a node: the cheapest
+compute SKU with at least --node-vcpu and --node-memory on each provider,
+like an EKS, GKE or AKS node pool. Each workload pays for its largest share
+of a node's CPU or memory. PersistentVolumeClaims and StatefulSet volume
+claim templates are priced as block storage.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		paths, _ := cmd.Flags().GetStringSlice("file")
+		objects, err := readK8sManifests(paths)
+		if err != nil {
+			return fmt.Errorf("reading manifests: %v", err)
+		}
+
+		opts := k8sOptions{}
+		opts.region, _ = cmd.Flags().GetString("region")
+		opts.hours, _ = cmd.Flags().GetFloat64("hours-per-month")
+		opts.nodeVCPU, _ = cmd.Flags().GetFloat64("node-vcpu")
+		opts.nodeMemory, _ = cmd.Flags().GetFloat64("node-memory")
+		opts.daemonSetNodes, _ = cmd.Flags().GetInt("daemonset-nodes")
+		opts.storageSize, _ = cmd.Flags().GetString("storage-size")
+		if err := checkHoursPerMonth(opts.hours); err != nil {
+			return withExitCode(exitUsage, err)
+		}
+
+		format, err := getOutputFormat(cmd)
+		if err != nil {
+			return err
+		}
+		if err := loadPricingData(cmd); err != nil {
+			return err
+		}
+		if opts.region != "" {
+			geos, err := resolveRegions([]string{opts.region}, prices.regions())
+			if err != nil {
+				return withExitCode(exitUsage, err)
+			}
+			opts.region = geos[0]
+		}
+
+		ke := estimateK8s(objects, opts)
+		if len(ke.Items) == 0 {
+			return fmt.Errorf("no Deployments, StatefulSets, DaemonSets or PersistentVolumeClaims found")
+		}
+		if err := writeK8sEstimate(os.Stdout, format, ke); err != nil {
+			return fmt.Errorf("writing estimate: %v", err)
+		}
+		return nil
+	},
+}
+
+// k8sOptions are the flags of estimate k8s
+type k8sOptions struct {
+	region         string
+	hours          float64
+	nodeVCPU       float64
+	nodeMemory     float64
+	daemonSetNodes int
+	storageSize    string
+}
+
+// k8sObject is the part of a Kubernetes object used for estimates
+type k8sObject struct {
+	Kind     string `yaml:"kind"`
+	Metadata struct {
+		Name      string `yaml:"name"`
+		Namespace string `yaml:"namespace"`
+	} `yaml:"metadata"`
+	Spec struct {
+		Replicas *int `yaml:"replicas"`
+		Template struct {
+			Spec struct {
+				Containers     []k8sContainer `yaml:"containers"`
+				InitContainers []k8sContainer `yaml:"initContainers"`
+			} `yaml:"spec"`
+		} `yaml:"template"`
+		VolumeClaimTemplates []k8sObject `yaml:"volumeClaimTemplates"`
+
+		// PersistentVolumeClaim fields
+		StorageClassName string       `yaml:"storageClassName"`
+		Resources        k8sResources `yaml:"resources"`
+	} `yaml:"spec"`
+	Items []k8sObject `yaml:"items"`
+}
+
+// k8sContainer is a container of a pod template
+type k8sContainer struct {
+	Name      string       `yaml:"name"`
+	Resources k8sResources `yaml:"resources"`
+}
+
+// k8sResources are the resource requests and limits of a container or claim
+type k8sResources struct {
+	Requests map[string]string `yaml:"requests"`
+	Limits   map[string]string `yaml:"limits"`
+}
+
+// k8sEstimate is an estimate of Kubernetes workloads with their cost per namespace
+// and the nodes they need on each provider. A namespace has no cost on a provider
+// that can't price all of its items.
+type k8sEstimate struct {
+	estimate   `yaml:",inline"`
+	Namespaces map[string]map[string]float64 `json:"namespaces" yaml:"namespaces"`
+	Nodes      map[string]k8sNodes           `json:"nodes" yaml:"nodes"`
+}
+
+// k8sNodes is the node pool a provider needs for the requested CPU and memory
+type k8sNodes struct {
+	SKU         string  `json:"sku" yaml:"sku"`
+	VCPU        float64 `json:"vcpu" yaml:"vcpu"`
+	MemoryGiB   float64 `json:"memory_gib" yaml:"memory_gib"`
+	Count       int     `json:"count" yaml:"count"`
+	MonthlyCost float64 `json:"monthly_cost" yaml:"monthly_cost"`
+}
+
+// k8sStorageClasses maps well-known storage classes of EKS, GKE and AKS to block storage sizes
+var k8sStorageClasses = map[string]string{
+	"gp2":                 "ssd",
+	"gp3":                 "ssd",
+	"io1":                 "ssd-iops",
+	"io2":                 "ssd-iops",
+	"st1":                 "hdd-throughput",
+	"sc1":                 "hdd-cold",
+	"standard-rwo":        "ssd",
+	"premium-rwo":         "ssd-iops",
+	"standard":            "hdd",
+	"default":             "ssd-standard",
+	"managed":             "ssd-standard",
+	"managed-csi":         "ssd-standard",
+	"managed-premium":     "ssd",
+	"managed-csi-premium": "ssd",
+}
+
+// readK8sManifests reads every Kubernetes object in the given files, directories
+// (recursively, *.yaml and *.yml) or stdin ("-"), expanding List objects
+func readK8sManifests(paths []string) ([]k8sObject, error) {
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no manifests given, use -f manifests/")
+	}
+	var objects []k8sObject
+	read := func(name string, data []byte) error {
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		for {
+			var obj k8sObject
+			err := dec.Decode(&obj)
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("%s: %v", name, err)
+			}
+			if obj.Kind == "List" || strings.HasSuffix(obj.Kind, "List") {
+				objects = append(objects, obj.Items...)
+				continue
+			}
+			objects = append(objects, obj)
+		}
+	}
+
+	for _, path := range paths {
+		if path == "-" {
+			data, err := io.ReadAll(os.Stdin)
+			if err != nil {
+				return nil, err
+			}
+			if err := read("stdin", data); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		err := filepath.WalkDir(path, func(name string, d os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			ext := filepath.Ext(name)
+			if d.IsDir() || (name != path && ext != ".yaml" && ext != ".yml") {
+				return nil
+			}
+			data, err := os.ReadFile(name)
+			if err != nil {
+				return err
+			}
+			return read(name, data)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return objects, nil
+}
+
+// estimateK8s prices the workloads and claims of Kubernetes objects on every provider
+func estimateK8s(objects []k8sObject, opts k8sOptions) k8sEstimate {
+	// The node each provider would run workloads on
+	nodes := map[string]SKU{}
+	for _, p := range prices.Providers {
+		if sku, ok := prices.equivalentSKU(p.Name, opts.region, opts.nodeVCPU, opts.nodeMemory); ok {
+			nodes[p.Name] = sku
+		}
+	}
+
+	var lines []estimateLine
+	var unpriced []string
+	namespaces := map[string]string{}
+	totalCPU, totalMemory := 0.0, 0.0
+	claim := func(name, namespace string, pvc k8sObject, count int) {
+		gb, err := parseK8sQuantity(pvc.Spec.Resources.Requests["storage"])
+		if err != nil || gb == 0 {
+			unpriced = append(unpriced, name+": no storage request")
+			return
+		}
+		size, ok := k8sStorageClasses[pvc.Spec.StorageClassName]
+		if !ok {
+			size = opts.storageSize
+		}
+		namespaces[name] = namespace
+		lines = append(lines, estimateLine{
+			item: estimateItem{Name: name, Region: opts.region, Service: "block-storage", Size: size, Quantity: gb / (1 << 30) * float64(count)},
+			skuFor: func(p string) (SKU, bool) {
+				return prices.lookupSKU(p, "block-storage", size, opts.region)
+			},
+		})
+	}
+
+	for _, obj := range objects {
+		namespace := obj.Metadata.Namespace
+		if namespace == "" {
+			namespace = "default"
+		}
+		name := namespace + "/" + obj.Metadata.Name
+
+		switch obj.Kind {
+		case "PersistentVolumeClaim":
+			claim(name, namespace, obj, 1)
+			continue
+		case "Deployment", "StatefulSet", "DaemonSet":
+		default:
+			continue
+		}
+
+		replicas := 1
+		if obj.Spec.Replicas != nil {
+			replicas = *obj.Spec.Replicas
+		}
+		if obj.Kind == "DaemonSet" {
+			replicas = opts.daemonSetNodes
+		}
+		cpu, memory, err := podRequests(obj.Spec.Template.Spec.Containers, obj.Spec.Template.Spec.InitContainers)
+		switch {
+		case err != nil:
+			unpriced = append(unpriced, fmt.Sprintf("%s: %v", name, err))
+		case cpu == 0 && memory == 0:
+			unpriced = append(unpriced, name+": no CPU or memory requests")
+		case replicas > 0:
+			totalCPU += cpu * float64(replicas)
+			totalMemory += memory * float64(replicas)
+			namespaces[name] = namespace
+			lines = append(lines, estimateLine{
+				item: estimateItem{Name: name, Region: opts.region, Service: "compute", Size: computeSize(cpu, memory), Quantity: float64(replicas)},
+				skuFor: func(p string) (SKU, bool) {
+					node, ok := nodes[p]
+					if !ok {
+						return SKU{}, false
+					}
+					v, m := skuShape(node)
+					// Each replica pays for its largest share of the node
+					node.Price *= math.Max(cpu/v, memory/m)
+					return node, true
+				},
+			})
+		}
+
+		for _, pvc := range obj.Spec.VolumeClaimTemplates {
+			claim(name+"/"+pvc.Metadata.Name, namespace, pvc, replicas)
+		}
+	}
+
+	ke := k8sEstimate{
+		estimate:   buildEstimate("Kubernetes workloads", opts.region, opts.hours, lines),
+		Namespaces: map[string]map[string]float64{},
+		Nodes:      map[string]k8sNodes{},
+	}
+	ke.Unpriced = append(unpriced, ke.Unpriced...)
+	partial := map[string]bool{} // namespace and provider pairs missing an item's cost
+	for _, item := range ke.Items {
+		if len(item.Costs) == 0 {
+			ke.Unpriced = append(ke.Unpriced, fmt.Sprintf("%s: no %s %s price on any provider", item.Name, item.Service, item.Size))
+		}
+		ns := namespaces[item.Name]
+		if ke.Namespaces[ns] == nil {
+			ke.Namespaces[ns] = map[string]float64{}
+		}
+		for _, p := range ke.Providers {
+			cost, ok := item.Costs[p]
+			if !ok {
+				partial[ns+"/"+p] = true
+				continue
+			}
+			ke.Namespaces[ns][p] = roundPrice(ke.Namespaces[ns][p] + cost)
+		}
+	}
+	for ns, costs := range ke.Namespaces {
+		for _, p := range ke.Providers {
+			if partial[ns+"/"+p] {
+				delete(costs, p)
+			}
+		}
+	}
+	for p, node := range nodes {
+		v, m := skuShape(node)
+		count := int(math.Ceil(math.Max(totalCPU/v, totalMemory/m)))
+		times, _, _ := perMonth(node.Unit)
+		if node.Unit == "hour" {
+			times = opts.hours
+		}
+		ke.Nodes[p] = k8sNodes{SKU: node.ID, VCPU: v, MemoryGiB: m, Count: count,
+			MonthlyCost: roundPrice(node.Price * currencyRate * times * float64(count))}
+	}
+	return ke
+}
+
+// podRequests returns the vCPUs and GiB of memory a pod requests: the sum of its
+// containers, or its largest init container if that is more. Containers without
+// requests fall back to their limits, as Kubernetes does.
+func podRequests(containers, initContainers []k8sContainer) (float64, float64, error) {
+	request := func(c k8sContainer, resource string) (float64, error) {
+		q, ok := c.Resources.Requests[resource]
+		if !ok {
+			q = c.Resources.Limits[resource]
+		}
+		v, err := parseK8sQuantity(q)
+		if err != nil {
+			return 0, fmt.Errorf("container %s: %s %v", c.Name, resource, err)
+		}
+		return v, nil
+	}
+
+	cpu, memory := 0.0, 0.0
+	for _, c := range containers {
+		v, err := request(c, "cpu")
+		if err != nil {
+			return 0, 0, err
+		}
+		m, err := request(c, "memory")
+		if err != nil {
+			return 0, 0, err
+		}
+		cpu += v
+		memory += m
+	}
+	for _, c := range initContainers {
+		v, err := request(c, "cpu")
+		if err != nil {
+			return 0, 0, err
+		}
+		m, err := request(c, "memory")
+		if err != nil {
+			return 0, 0, err
+		}
+		cpu = math.Max(cpu, v)
+		memory = math.Max(memory, m)
+	}
+	return cpu, memory / (1 << 30), nil
+}
+
+// k8sQuantityPattern matches a Kubernetes resource quantity such as 500m, 1.5Gi or 1e9
+var k8sQuantityPattern = regexp.MustCompile(`^([0-9.]+(?:[eE][-+]?[0-9]+)?)([a-zA-Z]*)$`)
+
+// k8sQuantitySuffixes are the multipliers of Kubernetes quantity suffixes
+var k8sQuantitySuffixes = map[string]float64{
+	"": 1, "m": 1e-3, "k": 1e3, "M": 1e6, "G": 1e9, "T": 1e12, "P": 1e15, "E": 1e18,
+	"Ki": 1 << 10, "Mi": 1 << 20, "Gi": 1 << 30, "Ti": 1 << 40, "Pi": 1 << 50, "Ei": 1 << 60,
+}
+
+// parseK8sQuantity parses a Kubernetes resource quantity into its base unit (cores
+// or bytes). An empty quantity is zero.
+func parseK8sQuantity(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	m := k8sQuantityPattern.FindStringSubmatch(s)
+	if m == nil {
+		return 0, fmt.Errorf("invalid quantity %q", s)
+	}
+	factor, ok := k8sQuantitySuffixes[m[2]]
+	if !ok {
+		return 0, fmt.Errorf("invalid quantity %q", s)
+	}
+	v, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid quantity %q", s)
+	}
+	return v * factor, nil
+}
+
+// skuShape returns the vCPUs and GiB of memory of a compute SKU
+func skuShape(sku SKU) (float64, float64) {
+	v, _ := strconv.ParseFloat(sku.Attributes["vcpu"], 64)
+	m, _ := strconv.ParseFloat(sku.Attributes["memory_gib"], 64)
+	return v, m
+}
+
+// writeK8sEstimate writes a Kubernetes estimate as a styled report or in a machine-readable format
+func writeK8sEstimate(w io.Writer, format string, ke k8sEstimate) error {
+	switch format {
+	case "json":
+		return writeJSON(w, ke)
+	case "yaml":
+		return writeYAML(w, ke)
+	case "csv":
+		return writeEstimate(w, format, ke.estimate)
+	}
+
+	var names []string
+	for ns := range ke.Namespaces {
+		names = append(names, ns)
+	}
+	sort.Strings(names)
+
+	if format == "markdown" {
+		if err := writeEstimate(w, format, ke.estimate); err != nil {
+			return err
+		}
+		fmt.Fprintln(w)
+		header := []string{"Namespace"}
+		for _, p := range ke.Providers {
+			header = append(header, currencyHeader(providerLabel(p), "/mo"))
+		}
+		var rows [][]string
+		for _, ns := range names {
+			row := []string{ns}
+			for _, p := range ke.Providers {
+				cost, ok := ke.Namespaces[ns][p]
+				if !ok {
+					row = append(row, "-")
+					continue
+				}
+				row = append(row, fmt.Sprintf("%.2f", cost))
+			}
+			rows = append(rows, row)
+		}
+		return writeMarkdownTable(w, header, rows)
+	}
+
+	printEstimateTable(w, ke.estimate)
+	for _, reason := range ke.Unpriced {
+		fmt.Fprintln(w, infoStyle.Render("Not priced: "+reason))
+	}
+
+	fmt.Fprintln(w)
+	header := fmt.Sprintf("%-20s", "Namespace")
+	for _, p := range ke.Providers {
+		header += fmt.Sprintf(" %13s", currencyHeader(providerLabel(p), "/mo"))
+	}
+	fmt.Fprintln(w, headerStyle.Render(header))
+	fmt.Fprintln(w, lineStyle.Render(strings.Repeat("-", 20+14*len(ke.Providers))))
+	for _, ns := range names {
+		row := fmt.Sprintf("%-20s", truncate(ns, 20))
+		for _, p := range ke.Providers {
+			cost, ok := ke.Namespaces[ns][p]
+			if !ok {
+				row += fmt.Sprintf(" %13s", "-")
+				continue
+			}
+			row += fmt.Sprintf(" %13.2f", cost)
+		}
+		fmt.Fprintln(w, row)
+	}
+	if len(ke.Incomplete) > 0 {
+		fmt.Fprintln(w, infoStyle.Render("Namespaces have no cost on providers that can't price every item in them."))
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, headerStyle.Render("Node equivalents"))
+	for _, p := range ke.Providers {
+		n, ok := ke.Nodes[p]
+		if !ok {
+			continue
+		}
+		fmt.Fprintf(w, "%-8s %d × %s (%s vCPU, %s GiB) = %s/month\n", providerLabel(p), n.Count, n.SKU,
+			formatQuantity(n.VCPU), formatQuantity(n.MemoryGiB), formatMoney(n.MonthlyCost))
+	}
+	return nil
+}
+
+func init() {
+	estimateK8sCmd.Flags().StringSliceP("file", "f", nil, "Manifest file or directory, or - for stdin (repeatable)")
+	estimateK8sCmd.Flags().String("region", "", "Region or geography to price the cluster in")
+	estimateK8sCmd.Flags().Float64("hours-per-month", defaultHoursPerMonth, "Hours a month nodes run")
+	estimateK8sCmd.Flags().Float64("node-vcpu", 4, "Minimum vCPUs of a node")
+	estimateK8sCmd.Flags().Float64("node-memory", 16, "Minimum GiB of memory of a node")
+	estimateK8sCmd.Flags().Int("daemonset-nodes", 3, "Nodes DaemonSets run a pod on")
+	estimateK8sCmd.Flags().String("storage-size", "ssd", "Block storage size for claims without a well-known storage class")
+	estimateCmd.AddCommand(estimateK8sCmd)
+}
@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseK8sQuantity(t *testing.T) {
+	tests := []struct {
+		s    string
+		want float64
+		err  bool
+	}{
+		{s: "500m", want: 0.5},
+		{s: "1.5", want: 1.5},
+		{s: "2Gi", want: 2 << 30},
+		{s: "128Mi", want: 128 << 20},
+		{s: "1G", want: 1e9},
+		{s: "1e3", want: 1000},
+		{s: "", want: 0},
+		{s: "two", err: true},
+		{s: "1Gb", err: true},
+		{s: "1.2.3", err: true},
+		{s: "-1", err: true},
+	}
+	for _, tt := range tests {
+		got, err := parseK8sQuantity(tt.s)
+		if tt.err {
+			if err == nil {
+				t.Errorf("parseK8sQuantity(%q) = %v, want an error", tt.s, got)
+			}
+			continue
+		}
+		if err != nil || math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("parseK8sQuantity(%q) = %v, %v; want %v", tt.s, got, err, tt.want)
+		}
+	}
+}
+
+func TestPodRequests(t *testing.T) {
+	container := func(name, cpu, memory string) k8sContainer {
+		c := k8sContainer{Name: name}
+		c.Resources.Requests = map[string]string{"cpu": cpu, "memory": memory}
+		return c
+	}
+	limitsOnly := k8sContainer{Name: "sidecar"}
+	limitsOnly.Resources.Limits = map[string]string{"cpu": "250m", "memory": "256Mi"}
+
+	tests := []struct {
+		name                   string
+		containers, init       []k8sContainer
+		wantCPU, wantMemoryGiB float64
+	}{
+		{"containers add up", []k8sContainer{container("web", "500m", "1Gi"), container("proxy", "100m", "128Mi")}, nil, 0.6, 1.125},
+		{"limits stand in for requests", []k8sContainer{container("web", "1", "1Gi"), limitsOnly}, nil, 1.25, 1.25},
+		{"a smaller init container", []k8sContainer{container("web", "1", "2Gi")}, []k8sContainer{container("migrate", "500m", "1Gi")}, 1, 2},
+		{"a larger init container", []k8sContainer{container("web", "500m", "1Gi")}, []k8sContainer{container("warm", "2", "512Mi")}, 2, 1},
+	}
+	for _, tt := range tests {
+		cpu, memory, err := podRequests(tt.containers, tt.init)
+		if err != nil || math.Abs(cpu-tt.wantCPU) > 1e-9 || math.Abs(memory-tt.wantMemoryGiB) > 1e-9 {
+			t.Errorf("%s: got %v vCPU, %v GiB, %v; want %v vCPU, %v GiB", tt.name, cpu, memory, err, tt.wantCPU, tt.wantMemoryGiB)
+		}
+	}
+
+	if _, _, err := podRequests([]k8sContainer{container("web", "lots", "1Gi")}, nil); err == nil || !strings.Contains(err.Error(), "container web") {
+		t.Errorf("got error %v, want one naming the container", err)
+	}
+}
+
+func TestEstimateK8sExamples(t *testing.T) {
+	useBuiltinCatalog(t)
+	objects, err := readK8sManifests([]string{"../examples/k8s"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ke := estimateK8s(objects, k8sOptions{hours: 730, nodeVCPU: 4, nodeMemory: 16, daemonSetNodes: 3, storageSize: "ssd"})
+
+	quantities := map[string]float64{}
+	for _, item := range ke.Items {
+		quantities[item.Name] = item.Quantity
+		if len(item.Costs) != len(ke.Providers) {
+			t.Errorf("%s is priced on %v, want every provider", item.Name, item.Costs)
+		}
+	}
+	// Replicas multiply pods and the claims of their volume claim templates
+	want := map[string]float64{
+		"shop/web": 6, "shop/postgres": 2, "shop/postgres/data": 400,
+		"monitoring/node-exporter": 3, "monitoring/prometheus-data": 100,
+	}
+	if !reflect.DeepEqual(quantities, want) {
+		t.Errorf("got quantities %v, want %v", quantities, want)
+	}
+	if len(ke.Unpriced) > 0 || len(ke.Incomplete) > 0 || len(ke.Totals) != len(ke.Providers) {
+		t.Errorf("got unpriced %v, incomplete %v and totals %v", ke.Unpriced, ke.Incomplete, ke.Totals)
+	}
+	if got := ke.Namespaces["shop"]["aws"]; math.Abs(got-167.93) > 0.005 {
+		t.Errorf("got an AWS cost of %v for shop, want 167.93", got)
+	}
+}
+
+func TestEstimateK8sUnpricedClaim(t *testing.T) {
+	useBuiltinCatalog(t)
+	pvc := k8sObject{Kind: "PersistentVolumeClaim"}
+	pvc.Metadata.Name, pvc.Metadata.Namespace = "logs", "ops"
+	pvc.Spec.StorageClassName = "st1" // throughput HDDs aren't in the built-in catalog
+	pvc.Spec.Resources.Requests = map[string]string{"storage": "500Gi"}
+
+	ke := estimateK8s([]k8sObject{pvc}, k8sOptions{hours: 730, nodeVCPU: 4, nodeMemory: 16, storageSize: "ssd"})
+	want := []string{"ops/logs: no block-storage hdd-throughput price on any provider"}
+	if !reflect.DeepEqual(ke.Unpriced, want) {
+		t.Errorf("got unpriced %v, want %v", ke.Unpriced, want)
+	}
+	if len(ke.Namespaces["ops"]) != 0 {
+		t.Errorf("got namespace costs %v that leave the claim out", ke.Namespaces["ops"])
+	}
+}
@@ -0,0 +1,550 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// estimateTerraformCmd estimates the monthly cost of a Terraform plan
+var estimateTerraformCmd = &cobra.Command{
+	Use:   "terraform <plan.json>",
+	Short: "Estimate the monthly cost of a Terraform plan and what it would cost on other clouds",
+	Long: `Estimate the monthly cost of a Terraform plan from the output of
+'terraform show -json plan.out' (use - to read it from stdin).
+
+Instances (aws_instance, google_compute_instance, azurerm_linux_virtual_machine)
+with their root disks, disks (aws_ebs_volume, google_compute_disk,
+azurerm_managed_disk) and buckets (aws_s3_bucket, google_storage_bucket,
+azurerm_storage_account) are priced on the provider they are defined for, and
+on every other provider with the cheapest equivalent SKU. Bucket sizes aren't
+in plans, so they are priced at --bucket-gb, and listed as not priced without
+it. The report compares the prior
+state with the planned state.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var data []byte
+		var err error
+		if args[0] == "-" {
+			data, err = io.ReadAll(os.Stdin)
+		} else {
+			data, err = os.ReadFile(args[0])
+		}
+		if err != nil {
+			return fmt.Errorf("reading plan: %v", err)
+		}
+		var plan terraformPlan
+		if err := json.Unmarshal(data, &plan); err != nil {
+			return fmt.Errorf("parsing plan '%s', expected 'terraform show -json' output: %v", args[0], err)
+		}
+
+		format, err := getOutputFormat(cmd)
+		if err != nil {
+			return err
+		}
+		if err := loadPricingData(cmd); err != nil {
+			return err
+		}
+
+		opts := terraformOptions{}
+		opts.region, _ = cmd.Flags().GetString("region")
+		opts.bucketGB, _ = cmd.Flags().GetFloat64("bucket-gb")
+		opts.hours, _ = cmd.Flags().GetFloat64("hours-per-month")
+		if opts.bucketGB < 0 {
+			return withExitCode(exitUsage, fmt.Errorf("--bucket-gb can't be negative"))
+		}
+		if err := checkHoursPerMonth(opts.hours); err != nil {
+			return withExitCode(exitUsage, err)
+		}
+		if opts.region != "" {
+			if _, err := resolveRegions([]string{opts.region}, prices.regions()); err != nil {
+				return withExitCode(exitUsage, err)
+			}
+		}
+
+		if err := writePlanEstimate(os.Stdout, format, estimatePlan(plan, opts)); err != nil {
+			return fmt.Errorf("writing estimate: %v", err)
+		}
+		return nil
+	},
+}
+
+// terraformPlan is the part of 'terraform show -json' output used for estimates
+type terraformPlan struct {
+	ResourceChanges []terraformResourceChange `json:"resource_changes"`
+	Configuration   struct {
+		ProviderConfig map[string]struct {
+			Name        string                            `json:"name"`
+			Expressions map[string]map[string]interface{} `json:"expressions"`
+		} `json:"provider_config"`
+	} `json:"configuration"`
+}
+
+// terraformResourceChange is a resource in a plan with its prior and planned values
+type terraformResourceChange struct {
+	Address string `json:"address"`
+	Mode    string `json:"mode"`
+	Type    string `json:"type"`
+	Change  struct {
+		Actions []string               `json:"actions"`
+		Before  map[string]interface{} `json:"before"`
+		After   map[string]interface{} `json:"after"`
+	} `json:"change"`
+}
+
+// terraformOptions are the flags of estimate terraform
+type terraformOptions struct {
+	region   string
+	bucketGB float64
+	hours    float64
+}
+
+// planEstimate compares the cost of the prior state of a plan with its planned state
+type planEstimate struct {
+	Prior     estimate           `json:"prior" yaml:"prior"`
+	Planned   estimate           `json:"planned" yaml:"planned"`
+	Delta     map[string]float64 `json:"monthly_delta" yaml:"monthly_delta"`
+	HomeDelta *float64           `json:"home_delta,omitempty" yaml:"home_delta,omitempty"` // nil while either state has unpriced resources
+}
+
+// terraformProviders maps resource type prefixes to catalog providers
+var terraformProviders = map[string]string{
+	"aws":     "aws",
+	"google":  "gcp",
+	"azurerm": "azure",
+}
+
+// terraformActions maps plan actions to the markers terraform plan uses
+var terraformActions = map[string]string{
+	"create":        "+",
+	"update":        "~",
+	"delete,create": "-/+",
+	"create,delete": "+/-",
+	"no-op":         "",
+}
+
+// azureDiskTypes maps managed disk storage account types to comparable block storage sizes
+var azureDiskTypes = map[string]string{
+	"Premium_LRS":     "ssd",
+	"PremiumV2_LRS":   "ssd",
+	"StandardSSD_LRS": "ssd-standard",
+	"Standard_LRS":    "hdd",
+}
+
+// gcpBucketClasses maps Cloud Storage bucket classes to comparable object storage sizes
+var gcpBucketClasses = map[string]string{
+	"STANDARD":       "standard",
+	"MULTI_REGIONAL": "standard",
+	"REGIONAL":       "standard",
+	"NEARLINE":       "infrequent",
+	"COLDLINE":       "archive-instant",
+	"ARCHIVE":        "archive",
+}
+
+// azureAccessTiers maps storage account access tiers to comparable object storage sizes
+var azureAccessTiers = map[string]string{
+	"Hot":  "standard",
+	"Cool": "infrequent",
+	"Cold": "archive-instant",
+}
+
+// estimatePlan prices the prior and planned state of a Terraform plan
+func estimatePlan(plan terraformPlan, opts terraformOptions) planEstimate {
+	var priorLines, plannedLines []estimateLine
+	var priorUnpriced, plannedUnpriced []string
+	for _, rc := range plan.ResourceChanges {
+		if rc.Mode != "managed" {
+			continue
+		}
+		action := terraformActions[strings.Join(rc.Change.Actions, ",")]
+		if rc.Change.Before != nil {
+			lines, unpriced := terraformLines(plan, rc, rc.Change.Before, opts)
+			priorLines = append(priorLines, lines...)
+			priorUnpriced = append(priorUnpriced, unpriced...)
+		}
+		if rc.Change.After != nil {
+			lines, unpriced := terraformLines(plan, rc, rc.Change.After, opts)
+			for i := range lines {
+				lines[i].item.Name = strings.TrimSpace(action + " " + lines[i].item.Name)
+			}
+			plannedLines = append(plannedLines, lines...)
+			plannedUnpriced = append(plannedUnpriced, unpriced...)
+		}
+	}
+
+	geo := ""
+	if opts.region != "" {
+		geo = regionGeography(opts.region)
+	}
+	pe := planEstimate{
+		Prior:   buildEstimate("prior state", geo, opts.hours, priorLines),
+		Planned: buildEstimate("planned state", geo, opts.hours, plannedLines),
+		Delta:   map[string]float64{},
+	}
+	pe.Prior.Unpriced = append(priorUnpriced, pe.Prior.Unpriced...)
+	pe.Planned.Unpriced = append(plannedUnpriced, pe.Planned.Unpriced...)
+	for _, est := range []*estimate{&pe.Prior, &pe.Planned} {
+		if len(est.Unpriced) > 0 {
+			est.HomeTotal = nil
+		}
+	}
+	for p, total := range pe.Planned.Totals {
+		if prior, ok := pe.Prior.Totals[p]; ok {
+			pe.Delta[p] = roundPrice(total - prior)
+		}
+	}
+	if pe.Prior.HomeTotal != nil && pe.Planned.HomeTotal != nil {
+		delta := roundPrice(*pe.Planned.HomeTotal - *pe.Prior.HomeTotal)
+		pe.HomeDelta = &delta
+	}
+	return pe
+}
+
+// terraformLines turns the values of a supported resource into estimate lines: an
+// instance and its root disk, a disk or a bucket. Resources that are supported but
+// can't be priced are returned as unpriced reasons; other resource types are ignored.
+func terraformLines(plan terraformPlan, rc terraformResourceChange, values map[string]interface{}, opts terraformOptions) ([]estimateLine, []string) {
+	home := terraformProviders[strings.SplitN(rc.Type, "_", 2)[0]]
+	region := terraformRegion(plan, home, values, opts.region)
+	geo := ""
+	if region != "" {
+		geo = regionGeography(region)
+	}
+
+	disk := func(name, size, homeID string, gb float64) estimateLine {
+		return estimateLine{
+			item: estimateItem{Name: name, Provider: home, Region: geo, Service: "block-storage", Size: size, Quantity: gb},
+			skuFor: func(p string) (SKU, bool) {
+				if p == home && homeID != "" {
+					if sku, ok := prices.skuByID(p, "block-storage", homeID, geo); ok {
+						return sku, true
+					}
+				}
+				return prices.lookupSKU(p, "block-storage", size, geo)
+			},
+		}
+	}
+	bucket := func(size string) ([]estimateLine, []string) {
+		if opts.bucketGB == 0 {
+			return nil, []string{rc.Address + ": bucket size is not in the plan, give it with --bucket-gb"}
+		}
+		return []estimateLine{{
+			item: estimateItem{Name: rc.Address, Provider: home, Region: geo, Service: "object-storage", Size: size, Quantity: opts.bucketGB},
+			skuFor: func(p string) (SKU, bool) {
+				return prices.lookupSKU(p, "object-storage", size, geo)
+			},
+		}}, nil
+	}
+
+	switch rc.Type {
+	case "aws_instance", "google_compute_instance", "azurerm_linux_virtual_machine":
+		var machine string
+		switch rc.Type {
+		case "aws_instance":
+			machine = stringValue(values, "instance_type")
+		case "google_compute_instance":
+			machine = stringValue(values, "machine_type")
+			machine = machine[strings.LastIndex(machine, "/")+1:]
+		default:
+			machine = stringValue(values, "size")
+		}
+		if machine == "" {
+			return nil, []string{rc.Address + ": machine type is not known until apply"}
+		}
+
+		vcpu, memory, ok := machineShape(home, machine, geo)
+		if !ok {
+			return nil, []string{fmt.Sprintf("%s: unknown machine type %s, import the %s price list", rc.Address, machine, providerLabel(home))}
+		}
+		lines := []estimateLine{{
+			item: estimateItem{Name: rc.Address, Provider: home, Region: geo, Service: "compute", Size: machine, Quantity: 1},
+			skuFor: func(p string) (SKU, bool) {
+				if p == home {
+					if sku, ok := prices.skuByID(p, "compute", machine, geo); ok {
+						return sku, true
+					}
+				}
+				return prices.equivalentSKU(p, geo, vcpu, memory)
+			},
+		}}
+
+		// Root disks are nested blocks of the instance
+		switch rc.Type {
+		case "aws_instance":
+			if root := firstBlock(values, "root_block_device"); root != nil && numberValue(root, "volume_size") > 0 {
+				volumeType := stringValue(root, "volume_type")
+				if volumeType == "" {
+					volumeType = "gp2"
+				}
+				lines = append(lines, disk(rc.Address+" root", awsVolumeSizes[volumeType], volumeType, numberValue(root, "volume_size")))
+			}
+		case "google_compute_instance":
+			if params := firstBlock(firstBlock(values, "boot_disk"), "initialize_params"); params != nil && numberValue(params, "size") > 0 {
+				lines = append(lines, gcpDiskLine(disk, rc.Address+" boot", stringValue(params, "type"), numberValue(params, "size")))
+			}
+		default:
+			if osDisk := firstBlock(values, "os_disk"); osDisk != nil && numberValue(osDisk, "disk_size_gb") > 0 {
+				lines = append(lines, disk(rc.Address+" os", azureDiskTypes[stringValue(osDisk, "storage_account_type")], "", numberValue(osDisk, "disk_size_gb")))
+			}
+		}
+		return lines, nil
+
+	case "aws_ebs_volume":
+		volumeType := stringValue(values, "type")
+		if volumeType == "" {
+			volumeType = "gp2"
+		}
+		return []estimateLine{disk(rc.Address, awsVolumeSizes[volumeType], volumeType, numberValue(values, "size"))}, nil
+	case "google_compute_disk":
+		return []estimateLine{gcpDiskLine(disk, rc.Address, stringValue(values, "type"), numberValue(values, "size"))}, nil
+	case "azurerm_managed_disk":
+		return []estimateLine{disk(rc.Address, azureDiskTypes[stringValue(values, "storage_account_type")], "", numberValue(values, "disk_size_gb"))}, nil
+
+	case "aws_s3_bucket":
+		return bucket("standard")
+	case "google_storage_bucket":
+		class := stringValue(values, "storage_class")
+		if class == "" {
+			class = "STANDARD"
+		}
+		return bucket(gcpBucketClasses[class])
+	case "azurerm_storage_account":
+		tier := stringValue(values, "access_tier")
+		if tier == "" {
+			tier = "Hot"
+		}
+		return bucket(azureAccessTiers[tier])
+	}
+	return nil, nil
+}
+
+// gcpDiskLine builds the estimate line of a persistent disk from its type
+func gcpDiskLine(disk func(name, size, homeID string, gb float64) estimateLine, name, diskType string, gb float64) estimateLine {
+	diskType = diskType[strings.LastIndex(diskType, "/")+1:]
+	if diskType == "" {
+		diskType = "pd-standard"
+	}
+	size := ""
+	for _, class := range gcpDiskClasses {
+		if class.id == diskType {
+			size = class.size
+		}
+	}
+	return disk(name, size, diskType, gb)
+}
+
+// terraformRegion finds the region of a resource from its zone or location, then from
+// its provider's configuration, then from --region
+func terraformRegion(plan terraformPlan, provider string, values map[string]interface{}, fallback string) string {
+	switch provider {
+	case "aws":
+		// A zone is its region with a letter, e.g. us-east-1a
+		az := stringValue(values, "availability_zone")
+		if region := strings.TrimRight(az, "abcdefghijklmnopqrstuvwxyz"); region != "" && region != az {
+			return region
+		}
+		if region := providerConfigValue(plan, "aws", "region"); region != "" {
+			return region
+		}
+	case "gcp":
+		// A zone is its region with a suffix, e.g. us-central1-a
+		if zone := stringValue(values, "zone"); strings.LastIndex(zone, "-") > 0 {
+			return zone[:strings.LastIndex(zone, "-")]
+		}
+		for _, key := range []string{"region", "location"} {
+			if region := stringValue(values, key); region != "" {
+				return strings.ToLower(region)
+			}
+		}
+		if region := providerConfigValue(plan, "google", "region"); region != "" {
+			return region
+		}
+	case "azure":
+		if location := stringValue(values, "location"); location != "" {
+			return strings.ToLower(strings.ReplaceAll(location, " ", ""))
+		}
+	}
+	return fallback
+}
+
+// providerConfigValue returns a constant argument of a provider block in the plan configuration
+func providerConfigValue(plan terraformPlan, provider, key string) string {
+	config, ok := plan.Configuration.ProviderConfig[provider]
+	if !ok {
+		return ""
+	}
+	value, _ := config.Expressions[key]["constant_value"].(string)
+	return value
+}
+
+// machineShape returns the vCPUs and GiB of memory of a machine type, from the
+// catalog when its SKU was imported, otherwise from its name
+func machineShape(provider, machine, geo string) (float64, float64, bool) {
+	if sku, ok := prices.skuByID(provider, "compute", machine, ""); ok {
+		v, err1 := strconv.ParseFloat(sku.Attributes["vcpu"], 64)
+		m, err2 := strconv.ParseFloat(sku.Attributes["memory_gib"], 64)
+		if err1 == nil && err2 == nil {
+			return v, m, true
+		}
+	}
+	switch provider {
+	case "aws":
+		return awsInstanceShape(machine)
+	case "gcp":
+		return gcpMachineShape(machine)
+	case "azure":
+		return azureVMShape(machine)
+	}
+	return 0, 0, false
+}
+
+// awsInstanceType matches general purpose, compute and memory optimized instance types such as m5.large
+var awsInstanceType = regexp.MustCompile(`^([mcr])\d+[a-z]*\.(\d*)x?large$`)
+
+// awsBurstableShapes are the vCPUs and GiB of memory of burstable t3 sizes
+var awsBurstableShapes = map[string][2]float64{
+	"nano": {2, 0.5}, "micro": {2, 1}, "small": {2, 2}, "medium": {2, 4},
+	"large": {2, 8}, "xlarge": {4, 16}, "2xlarge": {8, 32},
+}
+
+// awsInstanceShape derives vCPUs and memory from an instance type name, e.g. m5.xlarge
+// has 4 vCPUs and 16 GiB. The boolean is false for families the CLI doesn't know.
+func awsInstanceShape(instanceType string) (float64, float64, bool) {
+	if strings.HasPrefix(instanceType, "t3") || strings.HasPrefix(instanceType, "t4g") {
+		shape, ok := awsBurstableShapes[instanceType[strings.Index(instanceType, ".")+1:]]
+		return shape[0], shape[1], ok
+	}
+	m := awsInstanceType.FindStringSubmatch(instanceType)
+	if m == nil {
+		return 0, 0, false
+	}
+	vcpu := 2.0
+	if strings.HasSuffix(instanceType, "xlarge") {
+		multiple := 1.0
+		if m[2] != "" {
+			multiple, _ = strconv.ParseFloat(m[2], 64)
+		}
+		vcpu = 4 * multiple
+	}
+	perVCPU := map[string]float64{"m": 4, "c": 2, "r": 8}[m[1]]
+	return vcpu, vcpu * perVCPU, true
+}
+
+// gcpMachineShape derives vCPUs and memory from a predefined machine type name, e.g.
+// n2-standard-4 has 4 vCPUs and 16 GiB
+func gcpMachineShape(machineType string) (float64, float64, bool) {
+	parts := strings.Split(machineType, "-")
+	if len(parts) != 3 {
+		return 0, 0, false
+	}
+	vcpu, err := strconv.ParseFloat(parts[2], 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	for _, mt := range gcpMachineTypes {
+		if mt.name == parts[1] {
+			return vcpu, vcpu * mt.memory, true
+		}
+	}
+	return 0, 0, false
+}
+
+// stringValue returns a string attribute of resource values
+func stringValue(values map[string]interface{}, key string) string {
+	s, _ := values[key].(string)
+	return s
+}
+
+// numberValue returns a numeric attribute of resource values
+func numberValue(values map[string]interface{}, key string) float64 {
+	n, _ := values[key].(float64)
+	return n
+}
+
+// firstBlock returns the first nested block of resource values, e.g. root_block_device
+func firstBlock(values map[string]interface{}, key string) map[string]interface{} {
+	blocks, _ := values[key].([]interface{})
+	if len(blocks) == 0 {
+		return nil
+	}
+	block, _ := blocks[0].(map[string]interface{})
+	return block
+}
+
+// writePlanEstimate writes a plan estimate as a styled report or in a machine-readable format
+func writePlanEstimate(w io.Writer, format string, pe planEstimate) error {
+	switch format {
+	case "json":
+		return writeJSON(w, pe)
+	case "yaml":
+		return writeYAML(w, pe)
+	case "csv", "markdown":
+		if err := writeEstimate(w, format, pe.Planned); err != nil {
+			return err
+		}
+		if format == "markdown" {
+			if pe.HomeDelta == nil {
+				_, err := fmt.Fprintf(w, "\nMonthly cost of the plan: unknown, %d resources are not priced\n", len(pe.Prior.Unpriced)+len(pe.Planned.Unpriced))
+				return err
+			}
+			_, err := fmt.Fprintf(w, "\nMonthly cost of the plan: %s → %s, %s\n",
+				formatMoney(*pe.Prior.HomeTotal), formatMoney(*pe.Planned.HomeTotal), formatDelta(*pe.Prior.HomeTotal, *pe.Planned.HomeTotal))
+			return err
+		}
+		return nil
+	}
+
+	printEstimateTable(w, pe.Planned)
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, headerStyle.Render(fmt.Sprintf("%-24s %12s %12s   %s", "Monthly cost", "Prior", "Planned", "Change")))
+	fmt.Fprintln(w, lineStyle.Render(strings.Repeat("-", 70)))
+	if pe.HomeDelta != nil {
+		fmt.Fprintf(w, "%-24s %12.2f %12.2f   %s\n", "As planned", *pe.Prior.HomeTotal, *pe.Planned.HomeTotal,
+			formatDelta(*pe.Prior.HomeTotal, *pe.Planned.HomeTotal))
+	} else {
+		fmt.Fprintf(w, "%-24s %12s %12s   %s\n", "As planned", "-", "-", "unknown, not every resource is priced")
+	}
+	for _, p := range pe.Planned.Providers {
+		prior, okPrior := pe.Prior.Totals[p]
+		planned, okPlanned := pe.Planned.Totals[p]
+		if !okPrior || !okPlanned {
+			continue
+		}
+		fmt.Fprintf(w, "%-24s %12.2f %12.2f   %s\n", "Everything on "+providerLabel(p), prior, planned, formatDelta(prior, planned))
+	}
+	for _, reason := range pe.Planned.Unpriced {
+		fmt.Fprintln(w, infoStyle.Render("Not priced: "+reason))
+	}
+	for _, reason := range pe.Prior.Unpriced {
+		if !contains(pe.Planned.Unpriced, reason) {
+			fmt.Fprintln(w, infoStyle.Render("Not priced in the prior state: "+reason))
+		}
+	}
+	return nil
+}
+
+// formatDelta formats the change between two monthly costs, e.g. "+$12.50 (+8.3%)"
+func formatDelta(before, after float64) string {
+	delta := after - before
+	s := formatMoney(delta)
+	if delta >= 0 {
+		s = "+" + s
+	}
+	if before != 0 {
+		s += fmt.Sprintf(" (%+.1f%%)", delta/before*100)
+	}
+	return s
+}
+
+func init() {
+	estimateTerraformCmd.Flags().String("region", "", "Region of resources whose region isn't in the plan")
+	estimateTerraformCmd.Flags().Float64("bucket-gb", 0, "GB stored in each bucket, since plans don't say (default: buckets aren't priced)")
+	estimateTerraformCmd.Flags().Float64("hours-per-month", defaultHoursPerMonth, "Hours a month instances run")
+	estimateCmd.AddCommand(estimateTerraformCmd)
+}
@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"testing"
+)
+
+// useBuiltinCatalog makes the built-in catalog the loaded one for a test
+func useBuiltinCatalog(t *testing.T) {
+	t.Helper()
+	c, _, err := parseCatalog(defaultCatalog)
+	if err != nil {
+		t.Fatalf("parsing the built-in catalog: %v", err)
+	}
+	saved := prices
+	prices = c
+	t.Cleanup(func() { prices = saved })
+}
+
+func readPlanFixture(t *testing.T) terraformPlan {
+	t.Helper()
+	data, err := os.ReadFile("../testdata/terraform/plan.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var plan terraformPlan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		t.Fatalf("parsing plan: %v", err)
+	}
+	return plan
+}
+
+// itemCosts returns the monthly cost of every item of an estimate on a provider, by name
+func itemCosts(est estimate, provider string) map[string]float64 {
+	costs := map[string]float64{}
+	for _, item := range est.Items {
+		if cost, ok := item.Costs[provider]; ok {
+			costs[item.Name] = cost
+		}
+	}
+	return costs
+}
+
+func TestEstimatePlan(t *testing.T) {
+	useBuiltinCatalog(t)
+	pe := estimatePlan(readPlanFixture(t), terraformOptions{bucketGB: 100, hours: 730})
+
+	// m5.large and m5.xlarge both need the built-in medium size, c5.large the small one
+	wantPrior := map[string]float64{
+		"aws_instance.web":      71.54,
+		"aws_instance.web root": 4,
+		"aws_s3_bucket.assets":  2.3,
+		"aws_instance.legacy":   35.04,
+	}
+	if got := itemCosts(pe.Prior, "aws"); !reflect.DeepEqual(got, wantPrior) {
+		t.Errorf("got prior AWS costs %v, want %v", got, wantPrior)
+	}
+	wantPlanned := map[string]float64{
+		"~ aws_instance.web":      71.54,
+		"~ aws_instance.web root": 4,
+		"+ aws_ebs_volume.data":   40,
+		"aws_s3_bucket.assets":    2.3,
+	}
+	if got := itemCosts(pe.Planned, "aws"); !reflect.DeepEqual(got, wantPlanned) {
+		t.Errorf("got planned AWS costs %v, want %v", got, wantPlanned)
+	}
+	if got := itemCosts(pe.Planned, "gcp")["+ aws_ebs_volume.data"]; got != 50 {
+		t.Errorf("got a GCP cost of %v for the new volume, want 50", got)
+	}
+
+	if len(pe.Prior.Unpriced) > 0 || len(pe.Planned.Unpriced) > 0 {
+		t.Errorf("got unpriced resources %v and %v", pe.Prior.Unpriced, pe.Planned.Unpriced)
+	}
+	if pe.Prior.HomeTotal == nil || *pe.Prior.HomeTotal != 112.88 || pe.Planned.HomeTotal == nil || *pe.Planned.HomeTotal != 117.84 {
+		t.Errorf("got home totals %v and %v, want 112.88 and 117.84", pe.Prior.HomeTotal, pe.Planned.HomeTotal)
+	}
+	if pe.HomeDelta == nil || *pe.HomeDelta != 4.96 {
+		t.Errorf("got home delta %v, want 4.96", pe.HomeDelta)
+	}
+}
+
+func TestEstimatePlanUnpriced(t *testing.T) {
+	useBuiltinCatalog(t)
+	plan := readPlanFixture(t)
+	// A disk type with no block storage size in the catalog
+	for i, rc := range plan.ResourceChanges {
+		if rc.Address == "aws_ebs_volume.data" {
+			plan.ResourceChanges[i].Change.After["type"] = "io9"
+		}
+	}
+	pe := estimatePlan(plan, terraformOptions{bucketGB: 100, hours: 730})
+
+	want := []string{"+ aws_ebs_volume.data: no block-storage price on AWS"}
+	if !reflect.DeepEqual(pe.Planned.Unpriced, want) {
+		t.Errorf("got unpriced %v, want %v", pe.Planned.Unpriced, want)
+	}
+	if pe.Planned.HomeTotal != nil || pe.HomeDelta != nil {
+		t.Errorf("got a planned home total %v and delta %v with an unpriced resource", pe.Planned.HomeTotal, pe.HomeDelta)
+	}
+	if pe.Prior.HomeTotal == nil {
+		t.Error("the prior state has every resource priced, but no home total")
+	}
+}
+
+func TestEstimatePlanWithoutBucketSize(t *testing.T) {
+	useBuiltinCatalog(t)
+	pe := estimatePlan(readPlanFixture(t), terraformOptions{hours: 730})
+
+	want := []string{"aws_s3_bucket.assets: bucket size is not in the plan, give it with --bucket-gb"}
+	if !reflect.DeepEqual(pe.Prior.Unpriced, want) || !reflect.DeepEqual(pe.Planned.Unpriced, want) {
+		t.Errorf("got unpriced %v and %v, want %v", pe.Prior.Unpriced, pe.Planned.Unpriced, want)
+	}
+	if _, ok := itemCosts(pe.Planned, "aws")["aws_s3_bucket.assets"]; ok {
+		t.Error("the bucket is priced without a size")
+	}
+	if pe.Prior.HomeTotal != nil || pe.Planned.HomeTotal != nil || pe.HomeDelta != nil {
+		t.Errorf("got home totals %v and %v and delta %v without bucket sizes", pe.Prior.HomeTotal, pe.Planned.HomeTotal, pe.HomeDelta)
+	}
+}
@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestQuantity(t *testing.T) {
+	tests := []struct {
+		in   string
+		want float64
+		err  string
+	}{
+		{in: "12", want: 12},
+		{in: "1.5", want: 1.5},
+		{in: "500 GB", want: 500},
+		{in: "500GiB", want: 500},
+		{in: "4TB", want: 4000},
+		{in: "4 tib", want: 4096},
+		{in: "2PB", want: 2000000},
+		{in: "1PiB", want: 1048576},
+		{in: "4XB", err: `unknown unit "XB"`},
+		{in: "-4TB", err: `invalid quantity "-4TB"`},
+		{in: "lots", err: `invalid quantity "lots"`},
+		{in: "[4]", err: "invalid quantity"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			var q quantity
+			err := yaml.Unmarshal([]byte(tt.in), &q)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("got %g, %v; want error %q", q, err, tt.err)
+				}
+				return
+			}
+			if err != nil || float64(q) != tt.want {
+				t.Errorf("got %g, %v; want %g", q, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadWorkloadSpecHours(t *testing.T) {
+	tests := []struct {
+		hours string
+		want  float64
+		err   bool
+	}{
+		{hours: "", want: 0},
+		{hours: "hours_per_month: 200", want: 200},
+		{hours: "hours_per_month: 744", want: 744},
+		{hours: "hours_per_month: 0", err: true},
+		{hours: "hours_per_month: -10", err: true},
+		{hours: "hours_per_month: 745", err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.hours, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "workload.yaml")
+			spec := tt.hours + "\nresources:\n  - service: compute\n    size: small\n    quantity: 1\n"
+			if err := os.WriteFile(path, []byte(spec), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			got, err := readWorkloadSpec(path)
+			if tt.err {
+				if err == nil || !strings.Contains(err.Error(), "hours per month must be between 0 and 744") {
+					t.Errorf("got %v, want an error about the hours per month", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readWorkloadSpec: %v", err)
+			}
+			if tt.want == 0 && got.HoursPerMonth != nil {
+				t.Errorf("got %g hours per month, want none", *got.HoursPerMonth)
+			}
+			if tt.want != 0 && (got.HoursPerMonth == nil || *got.HoursPerMonth != tt.want) {
+				t.Errorf("got %v hours per month, want %g", got.HoursPerMonth, tt.want)
+			}
+		})
+	}
+}
+
+func TestEstimateWorkloadHours(t *testing.T) {
+	useBuiltinCatalog(t)
+	hours := 365.0
+	spec := workloadSpec{HoursPerMonth: &hours, Resources: []workloadResource{
+		{Name: "app", Service: "compute", Size: "medium", Quantity: 2},
+		{Name: "data", Service: "storage", Size: "medium", Quantity: 1000},
+	}}
+	half, err := estimateWorkload(spec)
+	if err != nil {
+		t.Fatalf("estimateWorkload: %v", err)
+	}
+	hours = 730
+	full, err := estimateWorkload(spec)
+	if err != nil {
+		t.Fatalf("estimateWorkload: %v", err)
+	}
+	if hoursPerMonth != defaultHoursPerMonth {
+		t.Errorf("estimating changed the hours per month to %g", hoursPerMonth)
+	}
+
+	// Hourly compute scales with the hours, storage priced per month doesn't
+	app, data := itemCosts(half, "aws"), itemCosts(full, "aws")
+	if got, want := app["app"], itemCosts(full, "aws")["app"]/2; math.Abs(got-want) > 0.005 {
+		t.Errorf("got %g for compute at %g hours, want %g", got, half.HoursPerMonth, want)
+	}
+	if app["data"] != data["data"] || data["data"] == 0 {
+		t.Errorf("got storage costs %g and %g, want the same at any hours", app["data"], data["data"])
+	}
+}
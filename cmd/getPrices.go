@@ -1,148 +1,414 @@
 package cmd
 
 import (
-	"encoding/json"
 	"fmt"
-	"io/ioutil"
+	"io"
+	"math"
 	"os"
+	"strconv"
 	"strings"
 
 	"github.com/charmbracelet/lipgloss"
 	"github.com/spf13/cobra"
 )
 
-// Data structure to hold the pricing information
-type PricingData struct {
-	AWS struct {
-		Compute map[string]float64 `json:"compute"`
-		Storage map[string]float64 `json:"storage"`
-	} `json:"aws"`
-	GCP struct {
-		Compute map[string]float64 `json:"compute"`
-		Storage map[string]float64 `json:"storage"`
-	} `json:"gcp"`
-	Azure struct {
-		Compute map[string]float64 `json:"compute"`
-		Storage map[string]float64 `json:"storage"`
-	} `json:"azure"`
-}
-
-// Pricing data
-var prices PricingData
+// Pricing catalog loaded by loadPricingData
+var prices Catalog
+
+// Geographies selected with --region; empty means every geography in the catalog
+var priceRegions []string
 
 // Define styles (same as before)
 var (
-	bestPriceColor   = lipgloss.Color("#0000FF")
-	highPriceColor   = lipgloss.Color("#FF4500")
-	mediumPriceColor = lipgloss.Color("#FFD700")
-	lowPriceColor    = lipgloss.Color("#00FFFF")
-	cellStyle        = lipgloss.NewStyle().Padding(0, 2)
-	headerStyle      = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("15"))
-	lineStyle        = lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+	cellStyle   = lipgloss.NewStyle().Padding(0, 2)
+	headerStyle = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("15"))
+	lineStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
 )
 
 // getPricesCmd represents the getPrices command
 var getPricesCmd = &cobra.Command{
 	Use:   "prices",
-	Short: "Get pricing for AWS, GCP, and Azure from a JSON file",
-	Run: func(cmd *cobra.Command, args []string) {
-		loadPricingData()
-		printLegend()
-		printPricingTable()
+	Short: "Get pricing for every provider in the pricing catalog",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		period, _ := cmd.Flags().GetString("period")
+		hours, _ := cmd.Flags().GetFloat64("hours-per-month")
+		if err := setPricePeriod(period, hours); err != nil {
+			return withExitCode(exitUsage, err)
+		}
+		model, _ := cmd.Flags().GetString("pricing-model")
+		if err := setPricingModel(model); err != nil {
+			return withExitCode(exitUsage, err)
+		}
+
+		format, err := getOutputFormat(cmd)
+		if err != nil {
+			return err
+		}
+
+		if err := loadPricingData(cmd); err != nil {
+			return err
+		}
+		regions, _ := cmd.Flags().GetStringSlice("region")
+		geos, err := resolveRegions(regions, prices.regions())
+		if err != nil {
+			return withExitCode(exitUsage, err)
+		}
+		priceRegions = geos
+
+		if interactive, _ := cmd.Flags().GetBool("interactive"); interactive {
+			if format != "table" {
+				return withExitCode(exitUsage, fmt.Errorf("--interactive can't be combined with --output %s", format))
+			}
+			return runPricesExplorer()
+		}
+		if len(catalogWarnings) > 0 {
+			fmt.Fprintln(os.Stderr, lineStyle.Render(fmt.Sprintf("The pricing catalog has %d warnings, run 'cloudcents catalog validate' for details", len(catalogWarnings))))
+		}
+		if format == "table" {
+			fmt.Println(lineStyle.Render("Catalog: " + catalogSource))
+			if note := currencyNote(); note != "" {
+				fmt.Println(lineStyle.Render(note))
+			}
+			if pricingModel != "on-demand" {
+				fmt.Println(lineStyle.Render("Pricing model: " + describePricingModel(pricingModel)))
+			}
+			printLegend()
+			printPricingTable()
+			if isCommitment(pricingModel) {
+				printBreakEvenTable()
+			}
+			return nil
+		}
+		if err := writePriceRecords(os.Stdout, format, priceRecords()); err != nil {
+			return fmt.Errorf("writing prices: %v", err)
+		}
+		return nil
 	},
 }
 
-// Load the pricing data from data.json
-func loadPricingData() {
-	file, err := os.Open("data.json")
-	if err != nil {
-		fmt.Println("Error opening data.json:", err)
-		return
-	}
-	defer file.Close()
+// priceRow identifies one row of the pricing table
+type priceRow struct {
+	service, size, region string
+}
 
-	byteValue, _ := ioutil.ReadAll(file)
-	json.Unmarshal(byteValue, &prices)
+// pricingRows lists the table rows for every service, size and geography being
+// compared that at least one provider prices in the selected pricing model
+func pricingRows(service string) []priceRow {
+	var rows []priceRow
+	for _, size := range prices.sizeNames(service) {
+		geos := priceRegions
+		if len(geos) == 0 {
+			geos = prices.geographies(service, size)
+		}
+		for _, geo := range geos {
+			for _, p := range prices.Providers {
+				if _, ok := prices.lookup(p.Name, service, size, geo); ok {
+					rows = append(rows, priceRow{service, size, geo})
+					break
+				}
+			}
+		}
+	}
+	return rows
 }
 
 // printPricingTable prints the pricing data in a styled table with a heatmap
 func printPricingTable() {
-	// Table header
-	header := fmt.Sprintf("%-10s %-15s %-10s %-10s %-10s", "Service", "Size", "AWS ($)", "GCP ($)", "Azure ($)")
+	rowsByService := map[string][]priceRow{}
+	showRegion := false
+	for _, service := range prices.serviceNames() {
+		rowsByService[service] = pricingRows(service)
+		for _, r := range rowsByService[service] {
+			if r.region != "" {
+				showRegion = true
+			}
+		}
+	}
+
+	header, width := pricingTableHeader(showRegion)
 	fmt.Println(headerStyle.Render(header))
-	fmt.Println(lineStyle.Render(strings.Repeat("-", 60)))
+	fmt.Println(lineStyle.Render(strings.Repeat("-", width)))
+
+	// Iterate through services, sizes and regions, and print prices for each provider with heatmap color-coding
+	for _, service := range prices.serviceNames() {
+		if len(rowsByService[service]) == 0 {
+			continue
+		}
+		for _, r := range rowsByService[service] {
+			fmt.Println(pricingTableRow(r, showRegion))
+		}
+		fmt.Println(lineStyle.Render(strings.Repeat("-", width))) // separator line after each service block
+	}
+}
+
+// pricingTableHeader returns the header of the pricing table, with one column per
+// provider in the catalog, and the width of the table
+func pricingTableHeader(showRegion bool) (string, int) {
+	header := fmt.Sprintf("%-15s %-15s", "Service", "Size")
+	width := 32 + 11 + 11*len(prices.Providers)
+	if showRegion {
+		header += fmt.Sprintf(" %-15s", "Region")
+		width += 16
+	}
+	header += fmt.Sprintf(" %-10s", "Per")
+	for _, p := range prices.Providers {
+		header += fmt.Sprintf(" %-10s", currencyHeader(p.DisplayName(), ""))
+	}
+	return header, width
+}
+
+// pricingTableRow renders a row of the pricing table with heatmap-colored prices
+func pricingTableRow(r priceRow, showRegion bool) string {
+	row := fmt.Sprintf("%-15s %-15s", r.service, r.size)
+	if showRegion {
+		row += fmt.Sprintf(" %-15s", r.region)
+	}
+	row += fmt.Sprintf(" %-10s", normalizedUnit(prices.unit(r.service, r.size)))
+	for _, p := range prices.Providers {
+		price, ok := prices.lookup(p.Name, r.service, r.size, r.region)
+		if !ok {
+			row += " " + cellStyle.Render(fmt.Sprintf("%6s", "-"))
+			continue
+		}
+		row += " " + stylePriceCell(price, r.service, r.size, r.region)
+	}
+	return row
+}
+
+// priceRecord is a row of the pricing table in machine-readable form
+type priceRecord struct {
+	Service  string             `json:"service" yaml:"service"`
+	Size     string             `json:"size" yaml:"size"`
+	Region   string             `json:"region,omitempty" yaml:"region,omitempty"`
+	Unit     string             `json:"unit,omitempty" yaml:"unit,omitempty"`
+	Currency string             `json:"currency" yaml:"currency"`
+	RateDate string             `json:"rate_date,omitempty" yaml:"rate_date,omitempty"`
+	Prices   map[string]float64 `json:"prices" yaml:"prices"`
+	Cheapest string             `json:"cheapest" yaml:"cheapest"`
+	Delta    map[string]float64 `json:"delta_to_best" yaml:"delta_to_best"`
 
-	// Services and sizes
-	services := []string{"compute", "storage"}
-	sizes := []string{"small", "medium", "large"}
+	// Models are the pricing models each provider's price is in, when not on-demand
+	Models map[string]string `json:"models,omitempty" yaml:"models,omitempty"`
+	// BreakEven are the hours of use per month from which a commitment beats on-demand
+	BreakEven map[string]float64 `json:"break_even_hours,omitempty" yaml:"break_even_hours,omitempty"`
+}
+
+// priceRecords returns the rows of the pricing table with the price of every
+// provider offering them, the cheapest provider and each provider's delta to it
+func priceRecords() []priceRecord {
+	var records []priceRecord
+	for _, service := range prices.serviceNames() {
+		for _, r := range pricingRows(service) {
+			rec := priceRecord{Service: r.service, Size: r.size, Region: r.region,
+				Unit:     normalizedUnit(prices.unit(r.service, r.size)),
+				Currency: displayCurrency, RateDate: rateDate,
+				Prices: map[string]float64{}, Delta: map[string]float64{}}
+			best := findBestPrice(r.service, r.size, r.region)
+			for _, p := range prices.Providers {
+				price, ok := prices.lookup(p.Name, r.service, r.size, r.region)
+				if !ok {
+					continue
+				}
+				rec.Prices[p.Name] = roundPrice(price)
+				rec.Delta[p.Name] = roundPrice(price - best)
+				if price == best && rec.Cheapest == "" {
+					rec.Cheapest = p.Name
+				}
+				if pricingModel == "on-demand" {
+					continue
+				}
+				if rec.Models == nil {
+					rec.Models = map[string]string{}
+				}
+				sku, _ := prices.lookupSKU(p.Name, r.service, r.size, r.region)
+				rec.Models[p.Name] = sku.Model
+				if hours, ok := rowBreakEven(p.Name, r); ok {
+					if rec.BreakEven == nil {
+						rec.BreakEven = map[string]float64{}
+					}
+					rec.BreakEven[p.Name] = math.Round(hours)
+				}
+			}
+			records = append(records, rec)
+		}
+	}
+	return records
+}
 
-	// Iterate through services and sizes, and print prices for each provider with heatmap color-coding
-	for _, service := range services {
-		for _, size := range sizes {
-			awsPrice := getPrice("aws", service, size)
-			gcpPrice := getPrice("gcp", service, size)
-			azurePrice := getPrice("azure", service, size)
+// writePriceRecords writes the pricing rows as JSON, YAML, CSV or Markdown
+func writePriceRecords(w io.Writer, format string, records []priceRecord) error {
+	switch format {
+	case "json":
+		return writeJSON(w, records)
+	case "yaml":
+		return writeYAML(w, records)
+	}
 
-			// Apply heatmap color based on price for each provider
-			awsStyledPrice := stylePriceCell(awsPrice, service, size)
-			gcpStyledPrice := stylePriceCell(gcpPrice, service, size)
-			azureStyledPrice := stylePriceCell(azurePrice, service, size)
+	// CSV and Markdown share one column per provider price and delta
+	header := []string{"service", "size", "region", "unit"}
+	for _, p := range prices.Providers {
+		header = append(header, p.Name)
+	}
+	header = append(header, "cheapest")
+	for _, p := range prices.Providers {
+		header = append(header, p.Name+"_delta")
+	}
+	header = append(header, "currency", "rate_date")
 
-			// Format the row with the styled prices
-			row := fmt.Sprintf("%-10s %-15s %-10s %-10s %-10s", service, size, awsStyledPrice, gcpStyledPrice, azureStyledPrice)
-			fmt.Println(row)
+	var rows [][]string
+	for _, rec := range records {
+		row := []string{rec.Service, rec.Size, rec.Region, rec.Unit}
+		var deltas []string
+		for _, p := range prices.Providers {
+			price, ok := rec.Prices[p.Name]
+			if !ok {
+				row = append(row, "")
+				deltas = append(deltas, "")
+				continue
+			}
+			row = append(row, strconv.FormatFloat(price, 'f', -1, 64))
+			deltas = append(deltas, strconv.FormatFloat(rec.Delta[p.Name], 'f', -1, 64))
 		}
-		fmt.Println(lineStyle.Render(strings.Repeat("-", 60))) // separator line after each service block
+		row = append(row, rec.Cheapest)
+		row = append(row, deltas...)
+		rows = append(rows, append(row, rec.Currency, rec.RateDate))
+	}
+
+	if format == "csv" {
+		return writeCSV(w, header, rows)
 	}
+	return writeMarkdownPrices(w, records)
 }
 
-// getPrice returns the price for a given provider, service, and size
-func getPrice(provider, service, size string) float64 {
-	switch provider {
-	case "aws":
-		if service == "compute" {
-			return prices.AWS.Compute[size]
+// writeMarkdownPrices writes the pricing rows as a Markdown table, with the best
+// price in bold and every other price followed by its increase over the best
+func writeMarkdownPrices(w io.Writer, records []priceRecord) error {
+	header := []string{"Service", "Size", "Region", "Per"}
+	for _, p := range prices.Providers {
+		header = append(header, currencyHeader(p.DisplayName(), ""))
+	}
+	header = append(header, "Cheapest")
+
+	var rows [][]string
+	for _, rec := range records {
+		row := []string{rec.Service, rec.Size, rec.Region, rec.Unit}
+		for _, p := range prices.Providers {
+			price, ok := rec.Prices[p.Name]
+			switch {
+			case !ok:
+				row = append(row, "-")
+			case p.Name == rec.Cheapest:
+				row = append(row, "**"+formatPrice(price)+"**")
+			case rec.Prices[rec.Cheapest] == 0:
+				row = append(row, formatPrice(price))
+			default:
+				row = append(row, fmt.Sprintf("%s (+%.1f%%)", formatPrice(price), rec.Delta[p.Name]/rec.Prices[rec.Cheapest]*100))
+			}
 		}
-		return prices.AWS.Storage[size]
-	case "gcp":
-		if service == "compute" {
-			return prices.GCP.Compute[size]
+		cheapest := rec.Cheapest
+		if pr := prices.provider(cheapest); pr != nil {
+			cheapest = pr.DisplayName()
 		}
-		return prices.GCP.Storage[size]
-	case "azure":
-		if service == "compute" {
-			return prices.Azure.Compute[size]
+		rows = append(rows, append(row, cheapest))
+	}
+	return writeMarkdownTable(w, header, rows)
+}
+
+// describePricingModel names a pricing model for the table, listing the models of a family
+func describePricingModel(model string) string {
+	if family, ok := pricingModelFamilies[model]; ok {
+		return fmt.Sprintf("%s (the cheapest of %s)", model, strings.Join(family, ", "))
+	}
+	return model
+}
+
+// rowBreakEven returns the hours of use per month from which a provider's price in
+// the selected commitment beats its on-demand price, for SKUs priced per hour
+func rowBreakEven(provider string, r priceRow) (float64, bool) {
+	if !isCommitment(pricingModel) {
+		return 0, false
+	}
+	committed, ok := prices.lookupSKU(provider, r.service, r.size, r.region)
+	if !ok || committed.Unit != "hour" {
+		return 0, false
+	}
+	onDemand, ok := prices.lookupModelSKU(provider, r.service, r.size, r.region, "on-demand")
+	if !ok || onDemand.Unit != "hour" {
+		return 0, false
+	}
+	return breakEvenHours(onDemand.Price, committed.Price)
+}
+
+// printBreakEvenTable prints how many hours per month each commitment needs to be
+// used to cost less than on-demand, for every row priced per hour
+func printBreakEvenTable() {
+	var rows []priceRow
+	for _, service := range prices.serviceNames() {
+		for _, r := range pricingRows(service) {
+			for _, p := range prices.Providers {
+				if _, ok := rowBreakEven(p.Name, r); ok {
+					rows = append(rows, r)
+					break
+				}
+			}
+		}
+	}
+	if len(rows) == 0 {
+		return
+	}
+
+	fmt.Println()
+	fmt.Println(headerStyle.Render(fmt.Sprintf("Break-even: hours of use per month from which %s costs less than on-demand (of %g)", pricingModel, hoursPerMonth)))
+	header := fmt.Sprintf("%-15s %-15s %-15s", "Service", "Size", "Region")
+	for _, p := range prices.Providers {
+		header += fmt.Sprintf(" %-12s", p.DisplayName())
+	}
+	width := 47 + 13*len(prices.Providers)
+	fmt.Println(headerStyle.Render(header))
+	fmt.Println(lineStyle.Render(strings.Repeat("-", width)))
+	for _, r := range rows {
+		row := fmt.Sprintf("%-15s %-15s %-15s", r.service, r.size, r.region)
+		for _, p := range prices.Providers {
+			hours, ok := rowBreakEven(p.Name, r)
+			cell := "-"
+			if ok {
+				cell = fmt.Sprintf("%.0fh (%.0f%%)", hours, hours/hoursPerMonth*100)
+			}
+			row += fmt.Sprintf(" %-12s", cell)
 		}
-		return prices.Azure.Storage[size]
+		fmt.Println(row)
 	}
-	return 0
+	fmt.Println(lineStyle.Render(strings.Repeat("-", width)))
+}
+
+// getPrice returns the price for a given provider, service, size and geography, or 0 if it is not offered
+func getPrice(provider, service, size, region string) float64 {
+	price, _ := prices.lookup(provider, service, size, region)
+	return price
 }
 
 // stylePriceCell styles a price cell based on its value and applies heatmap colors
-func stylePriceCell(price float64, service, size string) string {
-	bestPrice := findBestPrice(service, size)
+func stylePriceCell(price float64, service, size, region string) string {
+	return heatmapCell(fmt.Sprintf("%6s", formatPrice(price)), price, findBestPrice(service, size, region))
+}
 
-	var color lipgloss.Color
-	switch {
-	case price == bestPrice:
-		color = bestPriceColor
-	case price <= bestPrice*1.2:
-		color = lowPriceColor
-	case price <= bestPrice*1.5:
-		color = mediumPriceColor
-	default:
-		color = highPriceColor
+// findBestPrice finds the best (lowest) price across all providers offering a given service and size in a geography
+func findBestPrice(service, size, region string) float64 {
+	var vals []float64
+	for _, p := range prices.Providers {
+		if price, ok := prices.lookup(p.Name, service, size, region); ok {
+			vals = append(vals, price)
+		}
 	}
-
-	return cellStyle.Copy().Background(color).Render(fmt.Sprintf("%.3f", price))
+	if len(vals) == 0 {
+		return 0
+	}
+	return min(vals...)
 }
 
-// findBestPrice finds the best (lowest) price across AWS, GCP, and Azure for a given service and size
-func findBestPrice(service, size string) float64 {
-	awsPrice := getPrice("aws", service, size)
-	gcpPrice := getPrice("gcp", service, size)
-	azurePrice := getPrice("azure", service, size)
-	return min(awsPrice, gcpPrice, azurePrice)
+// roundPrice rounds a computed price to a millionth of a dollar to drop floating-point noise
+func roundPrice(v float64) float64 {
+	return math.Round(v*1e6) / 1e6
 }
 
 // min returns the minimum value from a list of floats
@@ -159,15 +425,15 @@ func min(vals ...float64) float64 {
 // printLegend prints a color-coded legend for the heatmap
 func printLegend() {
 	fmt.Println("\nLegend:")
-	legend := cellStyle.Copy().Background(bestPriceColor).Render(" Best Price ") + " " +
-		cellStyle.Copy().Background(lowPriceColor).Render(" Lower Price ") + " " +
-		cellStyle.Copy().Background(mediumPriceColor).Render(" Moderate Price ") + " " +
-		cellStyle.Copy().Background(highPriceColor).Render(" Higher Price ")
-
-	fmt.Println(legend)
-	fmt.Println("\n")
+	fmt.Println(legendView())
+	fmt.Print("\n\n")
 }
 
 func init() {
+	getPricesCmd.Flags().String("period", "", "Show prices per hour, month or year instead of their catalog units")
+	getPricesCmd.Flags().Float64("hours-per-month", defaultHoursPerMonth, "Hours a month of compute is billed for when converting hourly prices")
+	getPricesCmd.Flags().BoolP("interactive", "i", false, "Explore prices in an interactive table: scroll, sort, filter, switch pricing models and pin rows")
+	getPricesCmd.Flags().String("pricing-model", "on-demand", "Compare prices in a pricing model: "+strings.Join(pricingModels, ", "))
+	getPricesCmd.Flags().StringSliceP("region", "r", nil, "Only compare prices in these regions or geographies (repeatable), e.g. us-east-1, westeurope, asia-southeast")
 	rootCmd.AddCommand(getPricesCmd)
 }
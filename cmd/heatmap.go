@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/spf13/cobra"
+)
+
+// heatLevel is how a price compares with the best price of its row
+type heatLevel int
+
+const (
+	heatBest heatLevel = iota
+	heatLower
+	heatModerate
+	heatHigher
+)
+
+// heatLevelNames are the names of the levels in the heatmap.colors settings
+var heatLevelNames = [...]string{"best", "lower", "moderate", "higher"}
+
+// heatmapPalette colors the price levels. Palettes with markers add a symbol to
+// every cell, so the heatmap can be read without telling colors apart.
+type heatmapPalette struct {
+	backgrounds [4]lipgloss.Color
+	foregrounds [4]lipgloss.Color
+	markers     bool
+}
+
+// heatMarkers are the symbols of the price levels in palettes with markers
+var heatMarkers = [...]string{"*", "+", "^", "!"}
+
+// heatmapPalettes are the palettes of --palette
+var heatmapPalettes = map[string]heatmapPalette{
+	// The original colors, with dark or light text chosen for contrast
+	"default": {
+		backgrounds: [4]lipgloss.Color{"#0000FF", "#00FFFF", "#FFD700", "#FF4500"},
+		foregrounds: [4]lipgloss.Color{"#FFFFFF", "#000000", "#000000", "#000000"},
+	},
+	// Okabe-Ito colors, distinguishable with the common kinds of color blindness
+	"colorblind": {
+		backgrounds: [4]lipgloss.Color{"#0072B2", "#56B4E9", "#F0E442", "#D55E00"},
+		foregrounds: [4]lipgloss.Color{"#FFFFFF", "#000000", "#000000", "#FFFFFF"},
+		markers:     true,
+	},
+	// No colors: levels are told apart by their markers only
+	"monochrome": {markers: true},
+}
+
+// heatmap is the palette and thresholds prices and estimates are drawn with.
+// Prices up to lower times the best price are lower, up to moderate times are
+// moderate and above that higher.
+var heatmap = struct {
+	palette         heatmapPalette
+	lower, moderate float64
+}{heatmapPalettes["default"], 1.2, 1.5}
+
+// setHeatmap configures the heatmap from --palette, --heatmap-thresholds and the
+// heatmap settings of the config. Without a terminal on stdout, or with NO_COLOR
+// set, the heatmap is drawn in monochrome.
+func setHeatmap(cmd *cobra.Command) error {
+	name, _ := cmd.Flags().GetString("palette")
+	palette, ok := heatmapPalettes[strings.ToLower(name)]
+	if !ok {
+		return withExitCode(exitUsage, fmt.Errorf("invalid palette '%s', expected one of %s", name, strings.Join(heatmapPaletteNames(), ", ")))
+	}
+	if palette.backgrounds[heatBest] != "" {
+		for i, level := range heatLevelNames {
+			if color := configValue("heatmap.colors." + level); color != "" {
+				palette.backgrounds[i] = lipgloss.Color(color)
+			}
+		}
+	}
+	if markers := configValue("heatmap.markers"); markers != "" {
+		palette.markers, _ = strconv.ParseBool(markers)
+	}
+	if os.Getenv("NO_COLOR") != "" || !isTerminal(os.Stdout) {
+		palette = heatmapPalettes["monochrome"]
+	}
+
+	thresholds, _ := cmd.Flags().GetFloat64Slice("heatmap-thresholds")
+	if len(thresholds) != 2 {
+		return withExitCode(exitUsage, fmt.Errorf("heatmap thresholds need two values, e.g. 1.2,1.5"))
+	}
+	lower, moderate := thresholds[0], thresholds[1]
+	if lower < 1 || moderate < lower {
+		return withExitCode(exitUsage, fmt.Errorf("invalid heatmap thresholds %g,%g: they are multiples of the best price, at least 1 and in increasing order", lower, moderate))
+	}
+
+	heatmap.palette, heatmap.lower, heatmap.moderate = palette, lower, moderate
+	return nil
+}
+
+// heatmapPaletteNames returns the names of the palettes, sorted
+func heatmapPaletteNames() []string {
+	var names []string
+	for name := range heatmapPalettes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// priceLevel returns the heatmap level of a price relative to the best price
+func priceLevel(price, bestPrice float64) heatLevel {
+	switch {
+	case price == bestPrice:
+		return heatBest
+	case price <= bestPrice*heatmap.lower:
+		return heatLower
+	case price <= bestPrice*heatmap.moderate:
+		return heatModerate
+	default:
+		return heatHigher
+	}
+}
+
+// heatStyle returns the cell style of a heatmap level
+func heatStyle(level heatLevel) lipgloss.Style {
+	style := cellStyle.Copy()
+	p := heatmap.palette
+	if p.backgrounds[level] != "" {
+		style = style.Background(p.backgrounds[level]).Foreground(p.foregrounds[level])
+	} else if level == heatBest {
+		style = style.Bold(true)
+	}
+	return style
+}
+
+// heatmapCell renders text with the heatmap color of a price relative to the best
+// price. Markers take the place of a column of padding, so cells keep their width.
+func heatmapCell(text string, price, bestPrice float64) string {
+	level := priceLevel(price, bestPrice)
+	style := heatStyle(level)
+	if heatmap.palette.markers {
+		return style.PaddingRight(1).Render(text + heatMarkers[level])
+	}
+	return style.Render(text)
+}
+
+// legendView renders the heatmap colors and what they mean
+func legendView() string {
+	labels := [...]string{
+		"Best Price",
+		fmt.Sprintf("Lower Price (≤%g×)", heatmap.lower),
+		fmt.Sprintf("Moderate Price (≤%g×)", heatmap.moderate),
+		fmt.Sprintf("Higher Price (>%g×)", heatmap.moderate),
+	}
+	var cells []string
+	for i, label := range labels {
+		if heatmap.palette.markers {
+			label = heatMarkers[i] + " " + label
+		}
+		cells = append(cells, heatStyle(heatLevel(i)).Render(" "+label+" "))
+	}
+	return strings.Join(cells, " ")
+}
+
+func init() {
+	rootCmd.PersistentFlags().String("palette", "default", "Heatmap palette: "+strings.Join(heatmapPaletteNames(), ", "))
+	rootCmd.PersistentFlags().Float64Slice("heatmap-thresholds", []float64{1.2, 1.5}, "Multiples of the best price where the heatmap turns moderate and higher")
+}
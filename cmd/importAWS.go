@@ -0,0 +1,280 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// catalogImportAWSCmd imports an AWS Price List bulk offer file
+var catalogImportAWSCmd = &cobra.Command{
+	Use:   "aws <offer-file.json>",
+	Short: "Import an AWS Price List bulk offer file (EC2, EBS and S3)",
+	Long: `Import an AWS Price List bulk offer file downloaded from
+https://pricing.us-east-1.amazonaws.com/offers/v1.0/aws/index.json.
+
+EC2 Linux shared-tenancy instances, EBS volumes and S3 storage classes are
+normalized into the local catalog with their on-demand and standard no-upfront
+reserved prices. Everything else in the file is ignored. No network access is needed.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		file, err := os.Open(args[0])
+		if err != nil {
+			return fmt.Errorf("opening offer file: %v", err)
+		}
+		defer file.Close()
+
+		provider, err := parseAWSOffer(file)
+		if err != nil {
+			return fmt.Errorf("parsing AWS offer file '%s': %v", args[0], err)
+		}
+		return importCatalog(args[0], provider)
+	},
+}
+
+// awsProduct is an entry of the "products" object of an offer file
+type awsProduct struct {
+	SKU           string            `json:"sku"`
+	ProductFamily string            `json:"productFamily"`
+	Attributes    map[string]string `json:"attributes"`
+}
+
+// awsTerm is an offer term of a product under "terms.OnDemand" or "terms.Reserved"
+type awsTerm struct {
+	PriceDimensions map[string]awsPriceDimension `json:"priceDimensions"`
+	TermAttributes  map[string]string            `json:"termAttributes"`
+}
+
+// awsPriceDimension is a single rate of an offer term
+type awsPriceDimension struct {
+	Unit         string            `json:"unit"`
+	BeginRange   string            `json:"beginRange"`
+	PricePerUnit map[string]string `json:"pricePerUnit"`
+}
+
+// awsUnits maps offer file units to catalog units
+var awsUnits = map[string]string{
+	"Hrs":   "hour",
+	"GB-Mo": "gb-month",
+}
+
+// awsVolumeSizes maps EBS volume API names to comparable block storage sizes
+var awsVolumeSizes = map[string]string{
+	"gp2":      "ssd",
+	"gp3":      "ssd",
+	"io1":      "ssd-iops",
+	"io2":      "ssd-iops",
+	"st1":      "hdd-throughput",
+	"sc1":      "hdd-cold",
+	"standard": "hdd",
+}
+
+// awsStorageClasses maps S3 volume types to comparable object storage sizes
+var awsStorageClasses = map[string]string{
+	"Standard":                          "standard",
+	"Standard - Infrequent Access":      "infrequent",
+	"One Zone - Infrequent Access":      "infrequent-one-zone",
+	"Glacier Instant Retrieval":         "archive-instant",
+	"Amazon Glacier":                    "archive",
+	"Glacier Flexible Retrieval":        "archive",
+	"Glacier Deep Archive":              "deep-archive",
+	"Amazon Simple Storage Service":     "standard",
+	"Standard - Infrequent Access (IA)": "infrequent",
+}
+
+// parseAWSOffer streams an offer file and normalizes its EC2, EBS and S3 SKUs.
+// Offer files list "products" before "terms", which lets terms of products we
+// don't import be skipped without holding multi-gigabyte files in memory.
+func parseAWSOffer(r io.Reader) (Provider, error) {
+	provider := Provider{Name: "aws", Label: "AWS"}
+	dec := json.NewDecoder(r)
+	products := map[string]SKU{}
+	skus := map[string][]SKU{}
+	var services []string
+
+	err := decodeObject(dec, func(key string) error {
+		switch key {
+		case "products":
+			return decodeObject(dec, func(id string) error {
+				var p awsProduct
+				if err := dec.Decode(&p); err != nil {
+					return err
+				}
+				if sku, ok := normalizeAWSProduct(p); ok {
+					products[id] = sku
+				}
+				return nil
+			})
+		case "terms":
+			return decodeObject(dec, func(termType string) error {
+				if termType != "OnDemand" && termType != "Reserved" {
+					return skipValue(dec)
+				}
+				return decodeObject(dec, func(id string) error {
+					base, ok := products[id]
+					if !ok {
+						return skipValue(dec)
+					}
+					var terms map[string]awsTerm
+					if err := dec.Decode(&terms); err != nil {
+						return err
+					}
+					for _, term := range terms {
+						sku, ok := awsTermSKU(base, termType, term)
+						if !ok {
+							continue
+						}
+						service := base.Attributes["service"]
+						delete(sku.Attributes, "service")
+						if _, seen := skus[service]; !seen {
+							services = append(services, service)
+						}
+						skus[service] = append(skus[service], sku)
+					}
+					return nil
+				})
+			})
+		default:
+			return skipValue(dec)
+		}
+	})
+	if err != nil {
+		return provider, err
+	}
+
+	for _, name := range services {
+		sortSKUs(skus[name])
+		provider.Services = append(provider.Services, Service{Name: name, SKUs: skus[name]})
+	}
+	if len(provider.Services) == 0 {
+		return provider, fmt.Errorf("no EC2, EBS or S3 prices found")
+	}
+	return provider, nil
+}
+
+// normalizeAWSProduct turns an EC2 instance, EBS volume or S3 storage product into
+// a catalog SKU without a price. The catalog service is kept in the "service" attribute.
+func normalizeAWSProduct(p awsProduct) (SKU, bool) {
+	attrs := p.Attributes
+	region := attrs["regionCode"]
+	if region == "" {
+		return SKU{}, false
+	}
+
+	switch {
+	case p.ProductFamily == "Compute Instance":
+		// Only Linux shared-tenancy instances without pre-installed software are comparable
+		if attrs["operatingSystem"] != "Linux" || attrs["tenancy"] != "Shared" || attrs["preInstalledSw"] != "NA" {
+			return SKU{}, false
+		}
+		if c := attrs["capacitystatus"]; c != "" && c != "Used" {
+			return SKU{}, false
+		}
+		vcpu, err := strconv.ParseFloat(attrs["vcpu"], 64)
+		if err != nil {
+			return SKU{}, false
+		}
+		memory, err := parseGiB(attrs["memory"])
+		if err != nil {
+			return SKU{}, false
+		}
+		return SKU{
+			ID:     attrs["instanceType"],
+			Size:   computeSize(vcpu, memory),
+			Region: region,
+			Attributes: map[string]string{
+				"service":    "compute",
+				"aws_sku":    p.SKU,
+				"family":     attrs["instanceFamily"],
+				"vcpu":       formatQuantity(vcpu),
+				"memory_gib": formatQuantity(memory),
+			},
+		}, true
+
+	case p.ProductFamily == "Storage" && attrs["servicecode"] == "AmazonEC2":
+		size, ok := awsVolumeSizes[attrs["volumeApiName"]]
+		if !ok {
+			return SKU{}, false
+		}
+		return SKU{
+			ID:     attrs["volumeApiName"],
+			Size:   size,
+			Region: region,
+			Attributes: map[string]string{
+				"service": "block-storage",
+				"aws_sku": p.SKU,
+			},
+		}, true
+
+	case p.ProductFamily == "Storage" && attrs["servicecode"] == "AmazonS3":
+		size, ok := awsStorageClasses[attrs["volumeType"]]
+		if !ok {
+			return SKU{}, false
+		}
+		return SKU{
+			ID:     "s3-" + size,
+			Size:   size,
+			Region: region,
+			Attributes: map[string]string{
+				"service": "object-storage",
+				"aws_sku": p.SKU,
+			},
+		}, true
+	}
+	return SKU{}, false
+}
+
+// awsTermSKU prices a normalized product with an on-demand or reserved term.
+// Only standard no-upfront reservations are imported, so the hourly rate is the whole price.
+func awsTermSKU(base SKU, termType string, term awsTerm) (SKU, bool) {
+	sku := base
+	sku.Attributes = map[string]string{}
+	for k, v := range base.Attributes {
+		sku.Attributes[k] = v
+	}
+
+	if termType == "Reserved" {
+		ta := term.TermAttributes
+		if ta["OfferingClass"] != "standard" || ta["PurchaseOption"] != "No Upfront" {
+			return SKU{}, false
+		}
+		switch ta["LeaseContractLength"] {
+		case "1yr":
+			sku.Model = "reserved-1y"
+		case "3yr":
+			sku.Model = "reserved-3y"
+		default:
+			return SKU{}, false
+		}
+	}
+
+	for _, d := range term.PriceDimensions {
+		unit, ok := awsUnits[d.Unit]
+		if !ok || (d.BeginRange != "" && d.BeginRange != "0") {
+			continue
+		}
+		price, err := strconv.ParseFloat(d.PricePerUnit["USD"], 64)
+		if err != nil || price <= 0 {
+			continue
+		}
+		sku.Unit = unit
+		sku.Price = price
+		return sku, true
+	}
+	return SKU{}, false
+}
+
+// parseGiB parses AWS memory attributes such as "8 GiB" or "0.5 GiB"
+func parseGiB(s string) (float64, error) {
+	s = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(s), "GiB"))
+	return strconv.ParseFloat(strings.ReplaceAll(s, ",", ""), 64)
+}
+
+func init() {
+	catalogImportCmd.AddCommand(catalogImportAWSCmd)
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"math"
+	"os"
+	"testing"
+)
+
+// findSKU returns the SKU of a provider's service with an ID, region and pricing model
+func findSKU(t *testing.T, p Provider, service, id, region, model string) SKU {
+	t.Helper()
+	for _, s := range p.Services {
+		if s.Name != service {
+			continue
+		}
+		for _, sku := range s.SKUs {
+			if sku.ID == id && sku.Region == region && sku.Model == model {
+				return sku
+			}
+		}
+	}
+	t.Fatalf("no %s SKU %s in %s with model %q", service, id, region, model)
+	return SKU{}
+}
+
+// skuCounts returns the number of SKUs of each service of a provider
+func skuCounts(p Provider) map[string]int {
+	counts := map[string]int{}
+	for _, s := range p.Services {
+		counts[s.Name] = len(s.SKUs)
+	}
+	return counts
+}
+
+// assertSKU checks the normalized fields of a SKU
+func assertSKU(t *testing.T, sku SKU, size, unit string, price float64) {
+	t.Helper()
+	if sku.Size != size || sku.Unit != unit || math.Abs(sku.Price-price) > 1e-9 {
+		t.Errorf("%s in %s (%q): got size %s, unit %s, price %v; want %s, %s, %v",
+			sku.ID, sku.Region, sku.Model, sku.Size, sku.Unit, sku.Price, size, unit, price)
+	}
+}
+
+func parseAWSFixture(t *testing.T, name string) Provider {
+	t.Helper()
+	f, err := os.Open("../testdata/aws/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	p, err := parseAWSOffer(f)
+	if err != nil {
+		t.Fatalf("parseAWSOffer(%s): %v", name, err)
+	}
+	return p
+}
+
+func TestParseAWSOfferEC2(t *testing.T) {
+	p := parseAWSFixture(t, "ec2-us-east-1.json")
+	if p.Name != "aws" {
+		t.Errorf("got provider %s, want aws", p.Name)
+	}
+	counts := skuCounts(p)
+	// 5 Linux instances with on-demand, 1y and 3y prices, and 5 EBS volume types
+	if counts["compute"] != 15 || counts["block-storage"] != 5 || len(counts) != 2 {
+		t.Errorf("got SKU counts %v", counts)
+	}
+
+	m5 := findSKU(t, p, "compute", "m5.large", "us-east-1", "")
+	assertSKU(t, m5, "2vcpu-8gb", "hour", 0.096)
+	if m5.Attributes["vcpu"] != "2" || m5.Attributes["memory_gib"] != "8" || m5.Attributes["service"] != "" {
+		t.Errorf("got m5.large attributes %v", m5.Attributes)
+	}
+	assertSKU(t, findSKU(t, p, "compute", "m5.large", "us-east-1", "reserved-1y"), "2vcpu-8gb", "hour", 0.06)
+	assertSKU(t, findSKU(t, p, "compute", "m5.large", "us-east-1", "reserved-3y"), "2vcpu-8gb", "hour", 0.041)
+	assertSKU(t, findSKU(t, p, "compute", "c5.xlarge", "us-east-1", ""), "4vcpu-8gb", "hour", 0.17)
+	assertSKU(t, findSKU(t, p, "block-storage", "gp3", "us-east-1", ""), "ssd", "gb-month", 0.08)
+	assertSKU(t, findSKU(t, p, "block-storage", "st1", "us-east-1", ""), "hdd-throughput", "gb-month", 0.045)
+}
+
+func TestParseAWSOfferS3(t *testing.T) {
+	p := parseAWSFixture(t, "s3-us-east-1.json")
+	counts := skuCounts(p)
+	if counts["object-storage"] != 5 || len(counts) != 1 {
+		t.Errorf("got SKU counts %v", counts)
+	}
+	// The first tier of tiered storage prices is imported
+	assertSKU(t, findSKU(t, p, "object-storage", "s3-standard", "us-east-1", ""), "standard", "gb-month", 0.023)
+	assertSKU(t, findSKU(t, p, "object-storage", "s3-deep-archive", "us-east-1", ""), "deep-archive", "gb-month", 0.00099)
+}
@@ -0,0 +1,302 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// catalogImportAzureCmd imports saved pages of the Azure Retail Prices API
+var catalogImportAzureCmd = &cobra.Command{
+	Use:   "azure <page.json>...",
+	Short: "Import saved Azure Retail Prices API pages (VMs, managed disks and blob storage)",
+	Long: `Import one or more saved responses of the Azure Retail Prices API
+(https://prices.azure.com/api/retail/prices), one page per file.
+
+Linux virtual machines, LRS managed disks and LRS block blob storage are
+normalized into the local catalog with their pay-as-you-go, reservation and
+spot prices. Everything else in the files is ignored. No network access is needed.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var pages []azurePricesPage
+		for _, path := range args {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("reading Azure prices page: %v", err)
+			}
+			var page azurePricesPage
+			if err := json.Unmarshal(data, &page); err != nil {
+				return fmt.Errorf("parsing Azure prices page '%s': %v", path, err)
+			}
+			pages = append(pages, page)
+		}
+
+		provider, err := parseAzurePrices(pages)
+		if err != nil {
+			return fmt.Errorf("importing Azure prices: %v", err)
+		}
+		if err := importCatalog(strings.Join(args, "', '"), provider); err != nil {
+			return err
+		}
+
+		if !azureExportComplete(pages) {
+			displayError("Every page has a NextPageLink, so the export looks incomplete. Save and import the remaining pages too.")
+		}
+		return nil
+	},
+}
+
+// azurePricesPage is one page of the Azure Retail Prices API
+type azurePricesPage struct {
+	Items        []azurePriceItem `json:"Items"`
+	NextPageLink string           `json:"NextPageLink"`
+}
+
+// azurePriceItem is a single meter of the Azure Retail Prices API
+type azurePriceItem struct {
+	CurrencyCode         string  `json:"currencyCode"`
+	TierMinimumUnits     float64 `json:"tierMinimumUnits"`
+	RetailPrice          float64 `json:"retailPrice"`
+	ArmRegionName        string  `json:"armRegionName"`
+	MeterName            string  `json:"meterName"`
+	ProductName          string  `json:"productName"`
+	SkuName              string  `json:"skuName"`
+	ServiceName          string  `json:"serviceName"`
+	UnitOfMeasure        string  `json:"unitOfMeasure"`
+	Type                 string  `json:"type"`
+	ReservationTerm      string  `json:"reservationTerm"`
+	IsPrimaryMeterRegion *bool   `json:"isPrimaryMeterRegion"`
+	ArmSkuName           string  `json:"armSkuName"`
+}
+
+// azureVMSize matches VM sizes such as Standard_D2s_v3, Standard_E4as_v5 or Standard_B2ms
+var azureVMSize = regexp.MustCompile(`^Standard_([A-Z]+)(\d+)([a-z]*)(?:_v(\d+))?$`)
+
+// azureVMShapes lists the vCPUs and GiB of memory of sizes that don't follow their family
+var azureVMShapes = map[string][2]float64{
+	"Standard_B1ls": {1, 0.5},
+	"Standard_B1s":  {1, 1},
+	"Standard_B1ms": {1, 2},
+}
+
+// azureMemoryPerVCPU is the GiB of memory per vCPU of general VM families
+var azureMemoryPerVCPU = map[string]float64{
+	"D": 4,
+	"E": 8,
+	"F": 2,
+}
+
+// azureDiskSizes maps managed disk tiers to their capacity in GiB
+var azureDiskSizes = map[string]float64{
+	"1": 4, "2": 8, "3": 16, "4": 32, "6": 64, "10": 128, "15": 256,
+	"20": 512, "30": 1024, "40": 2048, "50": 4096, "60": 8192, "70": 16384, "80": 32767,
+}
+
+// azureDiskClasses maps managed disk products to comparable block storage sizes
+var azureDiskClasses = map[string]string{
+	"Premium SSD Managed Disks":  "ssd",
+	"Standard SSD Managed Disks": "ssd-standard",
+	"Standard HDD Managed Disks": "hdd",
+}
+
+// azureBlobTiers maps blob access tiers to comparable object storage sizes
+var azureBlobTiers = map[string]string{
+	"Hot":     "standard",
+	"Cool":    "infrequent",
+	"Cold":    "archive-instant",
+	"Archive": "archive",
+}
+
+// parseAzurePrices normalizes the VM, managed disk and blob storage meters of Retail Prices API pages
+func parseAzurePrices(pages []azurePricesPage) (Provider, error) {
+	provider := Provider{Name: "azure", Label: "Azure"}
+	skus := map[string][]SKU{}
+	seen := map[string]int{}
+	var services []string
+
+	for _, page := range pages {
+		for _, item := range page.Items {
+			if item.CurrencyCode != "" && item.CurrencyCode != "USD" {
+				return provider, fmt.Errorf("prices are in %s, only USD exports can be imported", item.CurrencyCode)
+			}
+			if item.IsPrimaryMeterRegion != nil && !*item.IsPrimaryMeterRegion {
+				continue
+			}
+			service, sku, ok := normalizeAzureItem(item)
+			if !ok {
+				continue
+			}
+
+			// Keep the lowest price when several meters describe the same SKU
+			key := strings.Join([]string{service, sku.ID, sku.Region, sku.Model}, "|")
+			if i, dup := seen[key]; dup {
+				if sku.Price < skus[service][i].Price {
+					skus[service][i] = sku
+				}
+				continue
+			}
+			if _, ok := skus[service]; !ok {
+				services = append(services, service)
+			}
+			seen[key] = len(skus[service])
+			skus[service] = append(skus[service], sku)
+		}
+	}
+
+	for _, name := range services {
+		sortSKUs(skus[name])
+		provider.Services = append(provider.Services, Service{Name: name, SKUs: skus[name]})
+	}
+	if len(provider.Services) == 0 {
+		return provider, fmt.Errorf("no virtual machine, managed disk or blob storage prices found")
+	}
+	return provider, nil
+}
+
+// azureExportComplete reports whether the pages include the last page of an
+// export. Every page links to the next one, so the last page has no link.
+func azureExportComplete(pages []azurePricesPage) bool {
+	for _, page := range pages {
+		if page.NextPageLink == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeAzureItem turns a Retail Prices API meter into a catalog service and SKU
+func normalizeAzureItem(item azurePriceItem) (string, SKU, bool) {
+	if item.ArmRegionName == "" || item.RetailPrice <= 0 {
+		return "", SKU{}, false
+	}
+	sku := SKU{Region: item.ArmRegionName}
+
+	switch {
+	case item.ServiceName == "Virtual Machines":
+		if strings.Contains(item.ProductName, "Windows") || strings.Contains(item.SkuName, "Low Priority") {
+			return "", SKU{}, false
+		}
+		vcpu, memory, ok := azureVMShape(item.ArmSkuName)
+		if !ok {
+			return "", SKU{}, false
+		}
+		hours, ok := azureHours(item.UnitOfMeasure)
+		if !ok {
+			return "", SKU{}, false
+		}
+
+		switch item.Type {
+		case "Consumption":
+			if strings.HasSuffix(item.SkuName, " Spot") {
+				sku.Model = "spot"
+			}
+			sku.Price = item.RetailPrice / hours
+		case "Reservation":
+			// Reservation prices are for the whole term
+			switch item.ReservationTerm {
+			case "1 Year":
+				sku.Model = "reserved-1y"
+				sku.Price = item.RetailPrice / (365 * 24)
+			case "3 Years":
+				sku.Model = "reserved-3y"
+				sku.Price = item.RetailPrice / (3 * 365 * 24)
+			default:
+				return "", SKU{}, false
+			}
+		default:
+			return "", SKU{}, false
+		}
+
+		sku.ID = item.ArmSkuName
+		sku.Size = computeSize(vcpu, memory)
+		sku.Unit = "hour"
+		sku.Attributes = map[string]string{
+			"vcpu":       formatQuantity(vcpu),
+			"memory_gib": formatQuantity(memory),
+		}
+		return "compute", sku, true
+
+	case item.ServiceName == "Storage" && azureDiskClasses[item.ProductName] != "":
+		// Managed disks are priced per disk and month, e.g. "P10 LRS Disk"
+		if item.Type != "Consumption" || item.UnitOfMeasure != "1/Month" || !strings.HasSuffix(item.MeterName, " LRS Disk") {
+			return "", SKU{}, false
+		}
+		tier := strings.TrimSuffix(item.MeterName, " LRS Disk")
+		gib, ok := azureDiskSizes[strings.TrimLeft(tier, "PES")]
+		if !ok || len(tier) < 2 {
+			return "", SKU{}, false
+		}
+		sku.ID = tier
+		sku.Size = azureDiskClasses[item.ProductName]
+		sku.Unit = "gb-month"
+		sku.Price = item.RetailPrice / gib
+		sku.Attributes = map[string]string{"disk_gib": formatQuantity(gib)}
+		return "block-storage", sku, true
+
+	case item.ServiceName == "Storage" && item.ProductName == "General Block Blob v2":
+		if item.Type != "Consumption" || item.UnitOfMeasure != "1 GB/Month" || item.TierMinimumUnits != 0 {
+			return "", SKU{}, false
+		}
+		tier := strings.TrimSuffix(item.MeterName, " LRS Data Stored")
+		size, ok := azureBlobTiers[tier]
+		if !ok || tier == item.MeterName {
+			return "", SKU{}, false
+		}
+		sku.ID = "blob-" + strings.ToLower(tier)
+		sku.Size = size
+		sku.Unit = "gb-month"
+		sku.Price = item.RetailPrice
+		return "object-storage", sku, true
+	}
+	return "", SKU{}, false
+}
+
+// azureVMShape derives vCPUs and memory from a VM size name, e.g. Standard_D2s_v3 has
+// 2 vCPUs and 8 GiB. The boolean is false for families the CLI doesn't know.
+func azureVMShape(armSkuName string) (float64, float64, bool) {
+	if shape, ok := azureVMShapes[armSkuName]; ok {
+		return shape[0], shape[1], true
+	}
+	m := azureVMSize.FindStringSubmatch(armSkuName)
+	if m == nil {
+		return 0, 0, false
+	}
+	family, features, version := m[1], m[3], m[4]
+	vcpu, err := strconv.ParseFloat(m[2], 64)
+	if err != nil || vcpu == 0 {
+		return 0, 0, false
+	}
+
+	switch {
+	case family == "B" && strings.HasSuffix(features, "ms"):
+		return vcpu, vcpu * 4, true
+	case family == "B" && strings.HasSuffix(features, "s"):
+		return vcpu, vcpu * 2, true
+	case family == "D" && version == "2":
+		return vcpu, vcpu * 3.5, true
+	}
+	perVCPU, ok := azureMemoryPerVCPU[family]
+	if !ok {
+		return 0, 0, false
+	}
+	return vcpu, vcpu * perVCPU, true
+}
+
+// azureHours returns how many hours a meter's unit of measure covers, e.g. 1 for "1 Hour"
+func azureHours(unit string) (float64, bool) {
+	fields := strings.Fields(unit)
+	if len(fields) != 2 || (fields[1] != "Hour" && fields[1] != "Hours") {
+		return 0, false
+	}
+	hours, err := strconv.ParseFloat(fields[0], 64)
+	return hours, err == nil && hours > 0
+}
+
+func init() {
+	catalogImportCmd.AddCommand(catalogImportAzureCmd)
+}
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+)
+
+func readAzureFixture(t *testing.T, name string) azurePricesPage {
+	t.Helper()
+	data, err := os.ReadFile("../testdata/azure/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var page azurePricesPage
+	if err := json.Unmarshal(data, &page); err != nil {
+		t.Fatalf("parsing %s: %v", name, err)
+	}
+	return page
+}
+
+func TestParseAzurePrices(t *testing.T) {
+	pages := []azurePricesPage{
+		readAzureFixture(t, "retail-prices-page-1.json"),
+		readAzureFixture(t, "retail-prices-page-2.json"),
+	}
+	p, err := parseAzurePrices(pages)
+	if err != nil {
+		t.Fatalf("parseAzurePrices: %v", err)
+	}
+	if p.Name != "azure" {
+		t.Errorf("got provider %s, want azure", p.Name)
+	}
+	counts := skuCounts(p)
+	// 5 Linux VM sizes in 2 regions with pay-as-you-go, 1y, 3y and spot prices;
+	// 6 disk tiers and 4 blob tiers in 2 regions
+	if counts["compute"] != 40 || counts["block-storage"] != 12 || counts["object-storage"] != 8 {
+		t.Errorf("got SKU counts %v", counts)
+	}
+
+	d2s := findSKU(t, p, "compute", "Standard_D2s_v3", "eastus", "")
+	assertSKU(t, d2s, "2vcpu-8gb", "hour", 0.096)
+	if d2s.Attributes["vcpu"] != "2" || d2s.Attributes["memory_gib"] != "8" {
+		t.Errorf("got Standard_D2s_v3 attributes %v", d2s.Attributes)
+	}
+	assertSKU(t, findSKU(t, p, "compute", "Standard_D2s_v3", "eastus", "spot"), "2vcpu-8gb", "hour", 0.0192)
+	// Reservation prices are for the whole term and are spread over its hours
+	assertSKU(t, findSKU(t, p, "compute", "Standard_B2s", "eastus", "reserved-1y"), "2vcpu-4gb", "hour", 225.94/(365*24))
+	// Managed disks are priced per disk and spread over its GiB
+	p10 := findSKU(t, p, "block-storage", "P10", "eastus", "")
+	assertSKU(t, p10, "ssd", "gb-month", 19.71/128)
+	if p10.Attributes["disk_gib"] != "128" {
+		t.Errorf("got P10 attributes %v", p10.Attributes)
+	}
+	assertSKU(t, findSKU(t, p, "object-storage", "blob-hot", "westeurope", ""), "standard", "gb-month", 0.01944)
+}
+
+func TestAzureNextPage(t *testing.T) {
+	first := readAzureFixture(t, "retail-prices-page-1.json")
+	last := readAzureFixture(t, "retail-prices-page-2.json")
+	if first.NextPageLink == "" || last.NextPageLink != "" {
+		t.Fatalf("fixtures should link page 1 to page 2, got %q and %q", first.NextPageLink, last.NextPageLink)
+	}
+
+	if azureExportComplete([]azurePricesPage{first}) {
+		t.Error("an export of only the first page should be incomplete")
+	}
+	if !azureExportComplete([]azurePricesPage{first, last}) {
+		t.Error("an export with the last page should be complete")
+	}
+
+	// The VMs are on the first page and the storage meters on the next one
+	p, err := parseAzurePrices([]azurePricesPage{first})
+	if err != nil {
+		t.Fatalf("parseAzurePrices: %v", err)
+	}
+	counts := skuCounts(p)
+	if counts["compute"] != 40 || counts["block-storage"] != 0 || counts["object-storage"] != 0 {
+		t.Errorf("got SKU counts %v from the first page", counts)
+	}
+}
@@ -0,0 +1,294 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// catalogImportGCPCmd imports saved pages of the Cloud Billing Catalog API
+var catalogImportGCPCmd = &cobra.Command{
+	Use:   "gcp <skus.json>...",
+	Short: "Import saved Cloud Billing Catalog API SKU pages (Compute Engine and Cloud Storage)",
+	Long: `Import one or more saved responses of the Cloud Billing Catalog API
+(https://cloudbilling.googleapis.com/v1/services/{service}/skus), one page per file.
+
+Compute Engine prices vCPUs and memory separately, so predefined standard,
+highmem and highcpu machine types are built from each family's core and RAM
+rates. Persistent disks and Cloud Storage classes are imported per GiB-month.
+On-demand, spot and committed use prices are imported; for tiered rates the
+first paid tier is used. No network access is needed.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var pages []gcpSKUPage
+		for _, path := range args {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("reading GCP SKU page: %v", err)
+			}
+			var page gcpSKUPage
+			if err := json.Unmarshal(data, &page); err != nil {
+				return fmt.Errorf("parsing GCP SKU page '%s': %v", path, err)
+			}
+			pages = append(pages, page)
+		}
+
+		provider, err := parseGCPSKUs(pages)
+		if err != nil {
+			return fmt.Errorf("importing GCP prices: %v", err)
+		}
+		if err := importCatalog(strings.Join(args, "', '"), provider); err != nil {
+			return err
+		}
+
+		// Every page has a token for the next one, except the last page of an export
+		complete := false
+		for _, page := range pages {
+			if page.NextPageToken == "" {
+				complete = true
+			}
+		}
+		if !complete {
+			displayError("Every page has a nextPageToken, so the export looks incomplete. Save and import the remaining pages too.")
+		}
+		return nil
+	},
+}
+
+// gcpSKUPage is one page of the Cloud Billing Catalog API services.skus.list method
+type gcpSKUPage struct {
+	SKUs          []gcpSKU `json:"skus"`
+	NextPageToken string   `json:"nextPageToken"`
+}
+
+// gcpSKU is a single SKU of the Cloud Billing Catalog API
+type gcpSKU struct {
+	SKUID       string `json:"skuId"`
+	Description string `json:"description"`
+	Category    struct {
+		ServiceDisplayName string `json:"serviceDisplayName"`
+		ResourceFamily     string `json:"resourceFamily"`
+		ResourceGroup      string `json:"resourceGroup"`
+		UsageType          string `json:"usageType"`
+	} `json:"category"`
+	ServiceRegions []string `json:"serviceRegions"`
+	PricingInfo    []struct {
+		PricingExpression struct {
+			UsageUnit   string `json:"usageUnit"`
+			TieredRates []struct {
+				StartUsageAmount float64  `json:"startUsageAmount"`
+				UnitPrice        gcpMoney `json:"unitPrice"`
+			} `json:"tieredRates"`
+		} `json:"pricingExpression"`
+	} `json:"pricingInfo"`
+}
+
+// gcpMoney is a google.type.Money amount split into whole units and nanos
+type gcpMoney struct {
+	CurrencyCode string `json:"currencyCode"`
+	Units        string `json:"units"`
+	Nanos        int64  `json:"nanos"`
+}
+
+// value returns the amount as a float
+func (m gcpMoney) value() float64 {
+	units, _ := strconv.ParseInt(m.Units, 10, 64)
+	return float64(units) + float64(m.Nanos)/1e9
+}
+
+// gcpModels maps SKU usage types to catalog pricing models
+var gcpModels = map[string]string{
+	"OnDemand":    "",
+	"Preemptible": "spot",
+	"Commit1Yr":   "cud-1y",
+	"Commit3Yr":   "cud-3y",
+}
+
+// gcpMachineFamily matches machine families such as E2, N2 or N2D in SKU descriptions
+var gcpMachineFamily = regexp.MustCompile(`\b([A-Z]\d[A-Z]?)\b`)
+
+// gcpMachineTypes are the predefined machine types built from core and RAM rates,
+// with their GiB of memory per vCPU
+var gcpMachineTypes = []struct {
+	name   string
+	memory float64
+}{
+	{"highcpu", 1},
+	{"standard", 4},
+	{"highmem", 8},
+}
+
+// gcpMachineVCPUs are the vCPU counts of the predefined machine types
+var gcpMachineVCPUs = []float64{2, 4, 8, 16, 32}
+
+// gcpDiskClasses maps persistent disk SKU descriptions to disk types and comparable block storage sizes
+var gcpDiskClasses = []struct {
+	prefix, id, size string
+}{
+	{"Storage PD Capacity", "pd-standard", "hdd"},
+	{"Balanced PD Capacity", "pd-balanced", "ssd"},
+	{"SSD backed PD Capacity", "pd-ssd", "ssd-iops"},
+}
+
+// gcpStorageClasses maps Cloud Storage resource groups to comparable object storage sizes
+var gcpStorageClasses = map[string]string{
+	"RegionalStorage":      "standard",
+	"MultiRegionalStorage": "standard",
+	"NearlineStorage":      "infrequent",
+	"ColdlineStorage":      "archive-instant",
+	"ArchiveStorage":       "archive",
+}
+
+// gcpRates collects the core and RAM rates of a machine family in a region
+type gcpRates struct {
+	family, region, model string
+	core, ram             float64
+}
+
+// parseGCPSKUs normalizes the Compute Engine and Cloud Storage SKUs of Catalog API pages
+func parseGCPSKUs(pages []gcpSKUPage) (Provider, error) {
+	provider := Provider{Name: "gcp", Label: "GCP"}
+	skus := map[string][]SKU{}
+	var services []string
+	add := func(service string, sku SKU) {
+		if _, ok := skus[service]; !ok {
+			services = append(services, service)
+		}
+		skus[service] = append(skus[service], sku)
+	}
+
+	rates := map[string]*gcpRates{}
+	var rateKeys []string
+	for _, page := range pages {
+		for _, s := range page.SKUs {
+			model, ok := gcpModels[s.Category.UsageType]
+			if !ok {
+				continue
+			}
+			price, ok := gcpFirstPaidRate(s)
+			if !ok {
+				continue
+			}
+
+			switch {
+			case s.Category.ServiceDisplayName == "Compute Engine" && s.Category.ResourceFamily == "Compute":
+				isCore := strings.Contains(s.Description, "Core") || strings.Contains(s.Description, "Cpu")
+				isRAM := strings.Contains(s.Description, "Ram")
+				if (!isCore && !isRAM) || strings.Contains(s.Description, "Custom") ||
+					strings.Contains(s.Description, "Sole Tenancy") || strings.Contains(s.Description, "Extended") {
+					continue
+				}
+				m := gcpMachineFamily.FindStringSubmatch(s.Description)
+				if m == nil {
+					continue
+				}
+				for _, region := range s.ServiceRegions {
+					key := strings.Join([]string{m[1], region, model}, "|")
+					r, ok := rates[key]
+					if !ok {
+						r = &gcpRates{family: m[1], region: region, model: model}
+						rates[key] = r
+						rateKeys = append(rateKeys, key)
+					}
+					if isCore {
+						r.core = price
+					} else {
+						r.ram = price
+					}
+				}
+
+			case s.Category.ServiceDisplayName == "Compute Engine" && s.Category.ResourceFamily == "Storage":
+				if strings.Contains(s.Description, "Regional") {
+					continue
+				}
+				for _, class := range gcpDiskClasses {
+					if !strings.HasPrefix(s.Description, class.prefix) {
+						continue
+					}
+					for _, region := range s.ServiceRegions {
+						add("block-storage", SKU{ID: class.id, Size: class.size, Region: region, Model: model, Unit: "gb-month", Price: price,
+							Attributes: map[string]string{"gcp_sku": s.SKUID}})
+					}
+				}
+
+			case s.Category.ServiceDisplayName == "Cloud Storage" && s.Category.ResourceFamily == "Storage":
+				size, ok := gcpStorageClasses[s.Category.ResourceGroup]
+				if !ok {
+					continue
+				}
+				for _, region := range s.ServiceRegions {
+					add("object-storage", SKU{ID: "gcs-" + strings.TrimSuffix(strings.ToLower(s.Category.ResourceGroup), "storage"),
+						Size: size, Region: region, Model: model, Unit: "gb-month", Price: price,
+						Attributes: map[string]string{"gcp_sku": s.SKUID}})
+				}
+			}
+		}
+	}
+
+	// Build predefined machine types from families with both a core and a RAM rate
+	for _, key := range rateKeys {
+		r := rates[key]
+		if r.core == 0 || r.ram == 0 {
+			continue
+		}
+		for _, mt := range gcpMachineTypes {
+			for _, vcpu := range gcpMachineVCPUs {
+				memory := vcpu * mt.memory
+				add("compute", SKU{
+					ID:     fmt.Sprintf("%s-%s-%s", strings.ToLower(r.family), mt.name, formatQuantity(vcpu)),
+					Size:   computeSize(vcpu, memory),
+					Region: r.region,
+					Model:  r.model,
+					Unit:   "hour",
+					Price:  vcpu*r.core + memory*r.ram,
+					Attributes: map[string]string{
+						"family":     strings.ToLower(r.family),
+						"vcpu":       formatQuantity(vcpu),
+						"memory_gib": formatQuantity(memory),
+					},
+				})
+			}
+		}
+	}
+
+	for _, name := range services {
+		sortSKUs(skus[name])
+		provider.Services = append(provider.Services, Service{Name: name, SKUs: skus[name]})
+	}
+	if len(provider.Services) == 0 {
+		return provider, fmt.Errorf("no Compute Engine or Cloud Storage prices found")
+	}
+	return provider, nil
+}
+
+// gcpFirstPaidRate returns the price per usage unit of the first paid tier, skipping
+// free tiers. Only hourly and GiB-month rates in USD are used.
+func gcpFirstPaidRate(s gcpSKU) (float64, bool) {
+	if len(s.PricingInfo) == 0 {
+		return 0, false
+	}
+	expr := s.PricingInfo[0].PricingExpression
+	switch expr.UsageUnit {
+	case "h", "GiBy.h", "GiBy.mo":
+	default:
+		return 0, false
+	}
+	for _, tier := range expr.TieredRates {
+		if c := tier.UnitPrice.CurrencyCode; c != "" && c != "USD" {
+			return 0, false
+		}
+		if price := tier.UnitPrice.value(); price > 0 {
+			return price, true
+		}
+	}
+	return 0, false
+}
+
+func init() {
+	catalogImportCmd.AddCommand(catalogImportGCPCmd)
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+)
+
+func readGCPFixture(t *testing.T, name string) gcpSKUPage {
+	t.Helper()
+	data, err := os.ReadFile("../testdata/gcp/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var page gcpSKUPage
+	if err := json.Unmarshal(data, &page); err != nil {
+		t.Fatalf("parsing %s: %v", name, err)
+	}
+	return page
+}
+
+func TestParseGCPSKUs(t *testing.T) {
+	p, err := parseGCPSKUs([]gcpSKUPage{
+		readGCPFixture(t, "compute-engine-skus.json"),
+		readGCPFixture(t, "cloud-storage-skus.json"),
+	})
+	if err != nil {
+		t.Fatalf("parseGCPSKUs: %v", err)
+	}
+	if p.Name != "gcp" {
+		t.Errorf("got provider %s, want gcp", p.Name)
+	}
+	counts := skuCounts(p)
+	// E2 and N2 in 2 regions with 4 pricing models, as 3 machine types of 5 sizes;
+	// 3 disk types and 4 storage classes in 2 regions
+	if counts["compute"] != 240 || counts["block-storage"] != 6 || counts["object-storage"] != 8 {
+		t.Errorf("got SKU counts %v", counts)
+	}
+
+	// Machine types are built from the family's core and RAM rates; custom and
+	// sole-tenancy rates are ignored
+	e2 := findSKU(t, p, "compute", "e2-standard-2", "us-east1", "")
+	assertSKU(t, e2, "2vcpu-8gb", "hour", 2*0.021811+8*0.002923)
+	if e2.Attributes["family"] != "e2" || e2.Attributes["vcpu"] != "2" || e2.Attributes["memory_gib"] != "8" {
+		t.Errorf("got e2-standard-2 attributes %v", e2.Attributes)
+	}
+	assertSKU(t, findSKU(t, p, "compute", "n2-standard-4", "us-east1", ""), "4vcpu-16gb", "hour", 4*0.031611+16*0.004237)
+	for _, model := range []string{"spot", "cud-1y", "cud-3y"} {
+		if sku := findSKU(t, p, "compute", "e2-standard-2", "us-east1", model); sku.Price >= e2.Price {
+			t.Errorf("%s price %v isn't below the on-demand price %v", model, sku.Price, e2.Price)
+		}
+	}
+
+	assertSKU(t, findSKU(t, p, "block-storage", "pd-balanced", "us-east1", ""), "ssd", "gb-month", 0.1)
+	// The free tier of Standard Storage is skipped for the first paid tier
+	assertSKU(t, findSKU(t, p, "object-storage", "gcs-regional", "us-east1", ""), "standard", "gb-month", 0.02)
+	assertSKU(t, findSKU(t, p, "object-storage", "gcs-archive", "europe-west1", ""), "archive", "gb-month", 0.0012)
+}
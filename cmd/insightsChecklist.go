@@ -2,7 +2,6 @@ package cmd
 
 import (
 	"fmt"
-	"os"
 	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
@@ -43,12 +42,12 @@ var (
 var checklistCmd = &cobra.Command{
 	Use:   "checklist",
 	Short: "View and complete a checklist of tasks",
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		p := tea.NewProgram(model{})
 		if err := p.Start(); err != nil {
-			fmt.Printf("Error starting program: %v\n", err)
-			os.Exit(1)
+			return fmt.Errorf("starting program: %v", err)
 		}
+		return nil
 	},
 }
 
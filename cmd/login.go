@@ -2,10 +2,7 @@ package cmd
 
 import (
 	"fmt"
-	"io/ioutil"
 	"os"
-	"path/filepath"
-	"runtime"
 
 	"github.com/charmbracelet/lipgloss"
 	"github.com/spf13/cobra"
@@ -14,52 +11,40 @@ import (
 // loginCmd represents the login command
 var loginCmd = &cobra.Command{
 	Use:   "login",
-	Short: "Login using the stored API key",
-	Run: func(cmd *cobra.Command, args []string) {
-		// Perform the login using the stored API key
-		err := checkStoredAPIKey()
+	Short: "Log in with the stored API key and show its account",
+	Long: `Log in with the API key of the active profile, or CLOUDCENTS_API_KEY, and verify
+it against the Cloud Cents API. Shows the account, plan and expiry of the key.
+
+With --device, log in in the browser instead of with an API key: cloudcents shows
+a code to enter on the Cloud Cents website and stores the tokens it gets once you
+approve. They are refreshed automatically before they expire.
+
+An invalid key exits with code 4, an expired key with 5 and a revoked key with 6.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if device, _ := cmd.Flags().GetBool("device"); device {
+			noBrowser, _ := cmd.Flags().GetBool("no-browser")
+			tokens, err := deviceLogin(!noBrowser)
+			if err != nil {
+				showLoginError(fmt.Sprintf("Error logging in: %v", err))
+				return withExitCode(authExitCode(err), nil)
+			}
+			if err := storeSecret(tokens.encode(), "Login"); err != nil {
+				return err
+			}
+		}
+
+		a, err := loginWithStoredAPIKey()
 		if err != nil {
 			showLoginError(fmt.Sprintf("Error logging in: %v", err))
-			return
+			if hint := authHint(err); hint != "" {
+				fmt.Fprintln(os.Stderr, infoStyle.Render(hint))
+			}
+			return withExitCode(authExitCode(err), nil)
 		}
-		showLoginSuccess("Login successful! You are now authenticated.")
-	},
-}
-
-// checkStoredAPIKey reads the stored API key and logs in
-func checkStoredAPIKey() error {
-	apiKey, err := loadAPIKey()
-	if err != nil {
-		return err
-	}
-
-	// Simulate login success if API key is found
-	if apiKey != "" {
+		showLoginSuccess(accountView(a))
 		return nil
-	}
-
-	return fmt.Errorf("API key not found")
-}
-
-// loadAPIKey reads the stored API key
-func loadAPIKey() (string, error) {
-	filePath := filepath.Join(getLoginConfigDir(), "api_key.txt")
-	apiKey, err := ioutil.ReadFile(filePath)
-	if err != nil {
-		return "", fmt.Errorf("could not read API key: %v", err)
-	}
-
-	return string(apiKey), nil
-}
-
-// getLoginConfigDir determines the OS-specific configuration directory
-func getLoginConfigDir() string {
-	switch runtime.GOOS {
-	case "windows":
-		return filepath.Join(os.Getenv("APPDATA"), "cloudcent")
-	default:
-		return filepath.Join(os.Getenv("HOME"), ".config", "cloudcent")
-	}
+	},
 }
 
 // showLoginSuccess formats and displays a success message with a border
@@ -72,16 +57,18 @@ func showLoginSuccess(message string) {
 	fmt.Println(borderStyle.Render(message))
 }
 
-// showLoginError formats and displays an error message with a border
+// showLoginError formats and displays an error message with a border on stderr
 func showLoginError(message string) {
 	borderStyle := lipgloss.NewStyle().
 		Border(lipgloss.RoundedBorder(), true).
 		Foreground(lipgloss.Color("9")). // Red for errors
 		Padding(1, 2)
 
-	fmt.Println(borderStyle.Render(message))
+	fmt.Fprintln(os.Stderr, borderStyle.Render(message))
 }
 
 func init() {
+	loginCmd.Flags().Bool("device", false, "Log in in the browser with a one-time code instead of an API key")
+	loginCmd.Flags().Bool("no-browser", false, "With --device, only print the link instead of opening a browser")
 	rootCmd.AddCommand(loginCmd)
 }
@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// logoutCmd removes the stored API key of the active profile
+var logoutCmd = &cobra.Command{
+	Use:   "logout",
+	Short: "Remove the stored API key of the active profile",
+	Long: `Remove the API key of the active profile from the OS keyring and the key file,
+which is overwritten before it is deleted. The profile's settings are kept; use
+'cloudcents profile delete' to remove a profile altogether.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		found, err := deleteAPIKey(currentProfile)
+		if err != nil {
+			return err
+		}
+		if found {
+			displaySuccess(fmt.Sprintf("Logged out of profile %s, its API key was removed", currentProfile))
+		} else {
+			fmt.Println(infoStyle.Render(fmt.Sprintf("Profile %s has no stored API key.", currentProfile)))
+		}
+		if os.Getenv("CLOUDCENTS_API_KEY") != "" {
+			fmt.Println(infoStyle.Render("CLOUDCENTS_API_KEY is set and is still used until you unset it."))
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(logoutCmd)
+}
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// outputFormats are the values accepted by the global --output flag
+var outputFormats = []string{"table", "json", "csv", "markdown", "yaml"}
+
+// getOutputFormat returns the validated value of the global --output flag
+func getOutputFormat(cmd *cobra.Command) (string, error) {
+	format, _ := cmd.Flags().GetString("output")
+	format = strings.ToLower(format)
+	for _, f := range outputFormats {
+		if f == format {
+			return format, nil
+		}
+	}
+	return "", withExitCode(exitUsage, fmt.Errorf("unknown output format %q, expected one of %s", format, strings.Join(outputFormats, ", ")))
+}
+
+// writeJSON writes v as indented JSON
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// writeYAML writes v as YAML
+func writeYAML(w io.Writer, v interface{}) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// writeCSV writes a header and rows as CSV
+func writeCSV(w io.Writer, header []string, rows [][]string) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	if err := cw.WriteAll(rows); err != nil {
+		return err
+	}
+	return cw.Error()
+}
+
+// writeMarkdownTable writes a header and rows as a GitHub-flavored Markdown table
+func writeMarkdownTable(w io.Writer, header []string, rows [][]string) error {
+	escape := func(cells []string) string {
+		escaped := make([]string, len(cells))
+		for i, c := range cells {
+			escaped[i] = strings.ReplaceAll(c, "|", "\\|")
+		}
+		return "| " + strings.Join(escaped, " | ") + " |\n"
+	}
+
+	separator := make([]string, len(header))
+	for i := range separator {
+		separator[i] = "---"
+	}
+
+	if _, err := io.WriteString(w, escape(header)+escape(separator)); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if _, err := io.WriteString(w, escape(row)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
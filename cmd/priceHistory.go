@@ -0,0 +1,514 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/spf13/cobra"
+)
+
+// priceSnapshot is one line of the price history: the SKUs of a provider's price
+// list as they were imported at a time. The history file is only ever appended to.
+type priceSnapshot struct {
+	Time     time.Time `json:"time"`
+	Source   string    `json:"source"`
+	Provider string    `json:"provider"`
+	Services []Service `json:"services"`
+}
+
+// priceSeries is the history of one SKU: its price in every snapshot it was in
+type priceSeries struct {
+	Provider string       `json:"provider" yaml:"provider"`
+	Service  string       `json:"service" yaml:"service"`
+	SKU      string       `json:"sku" yaml:"sku"` // the SKU's ID, or its size for SKUs without one
+	Size     string       `json:"size" yaml:"size"`
+	Region   string       `json:"region,omitempty" yaml:"region,omitempty"`
+	Model    string       `json:"model" yaml:"model"`
+	Unit     string       `json:"unit,omitempty" yaml:"unit,omitempty"`
+	Points   []pricePoint `json:"points" yaml:"points"`
+}
+
+// pricePoint is the price of a SKU in one snapshot, with its change to the previous snapshot
+type pricePoint struct {
+	Time      time.Time `json:"time" yaml:"time"`
+	Price     float64   `json:"price" yaml:"price"`
+	ChangePct float64   `json:"change_pct" yaml:"change_pct"`
+}
+
+// priceChange is a SKU whose price changed since a date
+type priceChange struct {
+	Provider  string    `json:"provider" yaml:"provider"`
+	Service   string    `json:"service" yaml:"service"`
+	SKU       string    `json:"sku" yaml:"sku"`
+	Region    string    `json:"region,omitempty" yaml:"region,omitempty"`
+	Model     string    `json:"model" yaml:"model"`
+	Unit      string    `json:"unit,omitempty" yaml:"unit,omitempty"`
+	Before    float64   `json:"before" yaml:"before"` // 0 for SKUs first imported since the date
+	After     float64   `json:"after" yaml:"after"`
+	ChangePct float64   `json:"change_pct" yaml:"change_pct"`
+	New       bool      `json:"new,omitempty" yaml:"new,omitempty"`
+	Changed   time.Time `json:"changed" yaml:"changed"` // the snapshot of the latest change
+	Currency  string    `json:"currency" yaml:"currency"`
+
+	trend []float64
+}
+
+// Styles of the sparklines: red for prices that went up, green for prices that went down
+var (
+	sparkBlocks    = []rune("▁▂▃▄▅▆▇█")
+	sparkUpStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("#D55E00"))
+	sparkDownStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#009E73"))
+)
+
+// pricesDiffCmd lists the prices that changed since a date
+var pricesDiffCmd = &cobra.Command{
+	Use:   "diff --since <date>",
+	Short: "Show the prices that changed since a date",
+	Long: `Show every SKU whose price changed since a date, comparing the last price
+imported before it with the latest one. SKUs first imported since the date are
+shown as new. The history is recorded by every 'catalog import'.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		since, _ := cmd.Flags().GetString("since")
+		if since == "" {
+			return withExitCode(exitUsage, fmt.Errorf("--since is needed, e.g. --since 2026-01-01"))
+		}
+		start, err := parseHistoryDate(since)
+		if err != nil {
+			return withExitCode(exitUsage, err)
+		}
+		format, err := getOutputFormat(cmd)
+		if err != nil {
+			return err
+		}
+		if err := setCurrency(cmd); err != nil {
+			return err
+		}
+
+		series, err := readPriceHistory()
+		if err != nil {
+			return err
+		}
+		filter, err := historyFilterFromFlags(cmd, series)
+		if err != nil {
+			return err
+		}
+		if len(series) == 0 {
+			fmt.Println(infoStyle.Render("No price history yet: it is recorded by every 'cloudcents catalog import'."))
+			return nil
+		}
+		var changes []priceChange
+		for _, s := range series {
+			if filter(s) {
+				if c, ok := s.changeSince(start); ok {
+					changes = append(changes, c)
+				}
+			}
+		}
+		return writePriceChanges(os.Stdout, format, since, changes)
+	},
+}
+
+// pricesHistoryCmd shows the price history of a SKU
+var pricesHistoryCmd = &cobra.Command{
+	Use:   "history <sku>",
+	Short: "Show how the price of a SKU changed over time",
+	Long: `Show every price change of a SKU, by its ID (e.g. m5.large) or its size
+(e.g. 2vcpu-8gb), in every provider, region and pricing model it was imported
+in. Narrow it down with --provider, --region and --pricing-model.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := getOutputFormat(cmd)
+		if err != nil {
+			return err
+		}
+		if err := setCurrency(cmd); err != nil {
+			return err
+		}
+
+		series, err := readPriceHistory()
+		if err != nil {
+			return err
+		}
+		filter, err := historyFilterFromFlags(cmd, series)
+		if err != nil {
+			return err
+		}
+		var matches []priceSeries
+		for _, s := range series {
+			if (strings.EqualFold(s.SKU, args[0]) || strings.EqualFold(s.Size, args[0])) && filter(s) {
+				matches = append(matches, s.converted())
+			}
+		}
+		if len(matches) == 0 {
+			return fmt.Errorf("no price history for '%s'; the history is recorded by every 'cloudcents catalog import'", args[0])
+		}
+		return writePriceHistory(os.Stdout, format, matches)
+	},
+}
+
+// priceHistoryPath returns the path of the price history file
+func priceHistoryPath() string {
+	return filepath.Join(getConfigDir(), "history.jsonl")
+}
+
+// recordPriceSnapshot appends the SKUs of an imported provider to the price history
+func recordPriceSnapshot(source string, imported Provider) error {
+	snapshot := priceSnapshot{
+		Time:     time.Now().UTC().Truncate(time.Second),
+		Source:   filepath.Base(source),
+		Provider: imported.Name,
+	}
+	// Attributes don't change prices, leave them out to keep the history small
+	for _, s := range imported.Services {
+		service := Service{Name: s.Name}
+		for _, sku := range s.SKUs {
+			sku.Attributes = nil
+			service.SKUs = append(service.SKUs, sku)
+		}
+		snapshot.Services = append(snapshot.Services, service)
+	}
+	line, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+
+	path := priceHistoryPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// readPriceHistory reads the price history into one series per SKU, sorted by
+// provider, service, SKU, region and model. A missing history is empty.
+func readPriceHistory() ([]priceSeries, error) {
+	f, err := os.Open(priceHistoryPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading price history: %v", err)
+	}
+	defer f.Close()
+
+	bySKU := map[string]*priceSeries{}
+	dec := json.NewDecoder(f)
+	for line := 1; ; line++ {
+		var snapshot priceSnapshot
+		if err := dec.Decode(&snapshot); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("invalid price history '%s', snapshot %d: %v", priceHistoryPath(), line, err)
+		}
+		for _, s := range snapshot.Services {
+			for _, sku := range s.SKUs {
+				name := sku.ID
+				if name == "" {
+					name = sku.Size
+				}
+				key := strings.Join([]string{snapshot.Provider, s.Name, name, sku.Region, sku.Model}, "\x00")
+				series, ok := bySKU[key]
+				if !ok {
+					series = &priceSeries{Provider: snapshot.Provider, Service: s.Name, SKU: name,
+						Size: sku.Size, Region: sku.Region, Model: sku.Model, Unit: sku.Unit}
+					bySKU[key] = series
+				}
+				series.Points = append(series.Points, pricePoint{Time: snapshot.Time, Price: sku.Price})
+			}
+		}
+	}
+
+	var series []priceSeries
+	for _, s := range bySKU {
+		sort.SliceStable(s.Points, func(i, j int) bool { return s.Points[i].Time.Before(s.Points[j].Time) })
+		for i := 1; i < len(s.Points); i++ {
+			s.Points[i].ChangePct = changePct(s.Points[i-1].Price, s.Points[i].Price)
+		}
+		if s.Model == "" {
+			s.Model = "on-demand"
+		}
+		series = append(series, *s)
+	}
+	sort.Slice(series, func(i, j int) bool {
+		a, b := series[i], series[j]
+		for _, pair := range [][2]string{{a.Provider, b.Provider}, {a.Service, b.Service}, {a.SKU, b.SKU}, {a.Region, b.Region}} {
+			if pair[0] != pair[1] {
+				return pair[0] < pair[1]
+			}
+		}
+		return a.Model < b.Model
+	})
+	return series, nil
+}
+
+// converted returns the series with its prices in the display currency
+func (s priceSeries) converted() priceSeries {
+	points := make([]pricePoint, len(s.Points))
+	for i, p := range s.Points {
+		points[i] = p
+		points[i].Price = roundPrice(p.Price * currencyRate)
+	}
+	s.Points = points
+	return s
+}
+
+// changeSince compares the last price of a series before a time with its latest
+// price. The boolean is false when the price is the same.
+func (s priceSeries) changeSince(start time.Time) (priceChange, bool) {
+	before := -1
+	for i, p := range s.Points {
+		if p.Time.Before(start) {
+			before = i
+		}
+	}
+	latest := s.Points[len(s.Points)-1]
+	c := priceChange{Provider: s.Provider, Service: s.Service, SKU: s.SKU, Region: s.Region, Model: s.Model,
+		Unit: s.Unit, After: roundPrice(latest.Price * currencyRate), Currency: displayCurrency}
+
+	if before < 0 {
+		// First imported since the date
+		c.New, c.Changed = true, s.Points[0].Time
+		before = 0
+	} else {
+		if latest.Price == s.Points[before].Price {
+			return c, false
+		}
+		c.Before = roundPrice(s.Points[before].Price * currencyRate)
+		c.ChangePct = changePct(s.Points[before].Price, latest.Price)
+	}
+	for i := before; i < len(s.Points); i++ {
+		c.trend = append(c.trend, s.Points[i].Price)
+		if i > before && s.Points[i].Price != s.Points[i-1].Price {
+			c.Changed = s.Points[i].Time
+		}
+	}
+	return c, true
+}
+
+// changePct returns the change from one price to another in percent, to a tenth
+func changePct(before, after float64) float64 {
+	if before == 0 {
+		return 0
+	}
+	return math.Round((after-before)/before*1000) / 10
+}
+
+// historyFilterFromFlags returns a filter for the series selected by --provider,
+// --region and --pricing-model. --region also accepts the regions of the series.
+func historyFilterFromFlags(cmd *cobra.Command, series []priceSeries) (func(priceSeries) bool, error) {
+	providers, _ := cmd.Flags().GetStringSlice("provider")
+	regions, _ := cmd.Flags().GetStringSlice("region")
+	var known []string
+	for _, s := range series {
+		known = append(known, s.Region)
+	}
+	geos, err := resolveRegions(regions, known)
+	if err != nil {
+		return nil, withExitCode(exitUsage, err)
+	}
+	model, _ := cmd.Flags().GetString("pricing-model")
+	if model != "" {
+		if err := setPricingModel(model); err != nil {
+			return nil, withExitCode(exitUsage, err)
+		}
+		model = pricingModel
+	}
+
+	return func(s priceSeries) bool {
+		if len(providers) > 0 && !contains(providers, s.Provider) {
+			return false
+		}
+		if len(geos) > 0 && s.Region != "" && !contains(geos, regionGeography(s.Region)) {
+			return false
+		}
+		skuModel := s.Model
+		if skuModel == "on-demand" {
+			skuModel = ""
+		}
+		return model == "" || modelMatches(skuModel, model)
+	}, nil
+}
+
+// parseHistoryDate parses the date of --since: a day such as 2026-01-01, which
+// starts at midnight UTC, or an RFC 3339 time
+func parseHistoryDate(s string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q, expected a day such as 2026-01-01", s)
+}
+
+// sparkline draws prices as a line of block characters, colored by whether the
+// last price is above or below the first
+func sparkline(values []float64) string {
+	if len(values) == 0 {
+		return ""
+	}
+	lo, hi := values[0], values[0]
+	for _, v := range values {
+		lo, hi = math.Min(lo, v), math.Max(hi, v)
+	}
+	var b strings.Builder
+	for _, v := range values {
+		i := 0
+		if hi > lo {
+			i = int(math.Round((v - lo) / (hi - lo) * float64(len(sparkBlocks)-1)))
+		}
+		b.WriteRune(sparkBlocks[i])
+	}
+	first, last := values[0], values[len(values)-1]
+	switch {
+	case last > first:
+		return sparkUpStyle.Render(b.String())
+	case last < first:
+		return sparkDownStyle.Render(b.String())
+	}
+	return lineStyle.Render(b.String())
+}
+
+// formatChangePct formats a change in percent with its sign, e.g. "+12.5%"
+func formatChangePct(pct float64) string {
+	return fmt.Sprintf("%+.1f%%", pct)
+}
+
+// writePriceChanges writes the prices that changed since a date in an output format
+func writePriceChanges(w io.Writer, format, since string, changes []priceChange) error {
+	switch format {
+	case "json":
+		return writeJSON(w, changes)
+	case "yaml":
+		return writeYAML(w, changes)
+	}
+
+	header := []string{"provider", "service", "sku", "region", "model", "unit", "before", "after", "change_pct", "changed", "currency"}
+	var rows [][]string
+	for _, c := range changes {
+		before, change := strconv.FormatFloat(c.Before, 'f', -1, 64), strconv.FormatFloat(c.ChangePct, 'f', -1, 64)
+		if c.New {
+			before, change = "", "new"
+		}
+		rows = append(rows, []string{c.Provider, c.Service, c.SKU, c.Region, c.Model, unitLabels[c.Unit],
+			before, strconv.FormatFloat(c.After, 'f', -1, 64), change, c.Changed.Format("2006-01-02"), c.Currency})
+	}
+	switch format {
+	case "csv":
+		return writeCSV(w, header, rows)
+	case "markdown":
+		return writeMarkdownTable(w, header, rows)
+	}
+
+	fmt.Fprintln(w, lineStyle.Render(fmt.Sprintf("Price changes since %s: %d", since, len(changes))))
+	if note := currencyNote(); note != "" {
+		fmt.Fprintln(w, lineStyle.Render(note))
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+	line := fmt.Sprintf("%-10s %-14s %-20s %-16s %-14s %-9s %10s %10s %8s  %-10s  %s",
+		"Provider", "Service", "SKU", "Region", "Model", "Per", currencyHeader("Before", ""), currencyHeader("After", ""), "Change", "Changed", "Trend")
+	fmt.Fprintln(w, headerStyle.Render(line))
+	fmt.Fprintln(w, lineStyle.Render(strings.Repeat("-", len(line))))
+	for _, c := range changes {
+		before, change := formatPrice(c.Before), formatChangePct(c.ChangePct)
+		if c.New {
+			before, change = "-", "new"
+		}
+		fmt.Fprintf(w, "%-10s %-14s %-20s %-16s %-14s %-9s %10s %10s %8s  %-10s  %s\n",
+			providerLabel(c.Provider), c.Service, truncate(c.SKU, 20), c.Region, c.Model, unitLabels[c.Unit],
+			before, formatPrice(c.After), change, c.Changed.Format("2006-01-02"), sparkline(c.trend))
+	}
+	return nil
+}
+
+// writePriceHistory writes the history of SKUs in an output format
+func writePriceHistory(w io.Writer, format string, series []priceSeries) error {
+	switch format {
+	case "json":
+		return writeJSON(w, series)
+	case "yaml":
+		return writeYAML(w, series)
+	}
+
+	header := []string{"provider", "service", "sku", "region", "model", "unit", "time", "price", "change_pct", "currency"}
+	var rows [][]string
+	for _, s := range series {
+		for _, p := range s.Points {
+			rows = append(rows, []string{s.Provider, s.Service, s.SKU, s.Region, s.Model, unitLabels[s.Unit],
+				p.Time.Format(time.RFC3339), strconv.FormatFloat(p.Price, 'f', -1, 64), strconv.FormatFloat(p.ChangePct, 'f', -1, 64), displayCurrency})
+		}
+	}
+	switch format {
+	case "csv":
+		return writeCSV(w, header, rows)
+	case "markdown":
+		return writeMarkdownTable(w, header, rows)
+	}
+
+	if note := currencyNote(); note != "" {
+		fmt.Fprintln(w, lineStyle.Render(note))
+	}
+	for i, s := range series {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		var values []float64
+		for _, p := range s.Points {
+			values = append(values, p.Price)
+		}
+		title := fmt.Sprintf("%s %s %s", providerLabel(s.Provider), s.Service, s.SKU)
+		if s.Region != "" {
+			title += " in " + s.Region
+		}
+		fmt.Fprintf(w, "%s  %s  %s\n", headerStyle.Render(title), lineStyle.Render(s.Model+", per "+unitLabels[s.Unit]), sparkline(values))
+
+		// Only list the snapshots where the price changed
+		for j, p := range s.Points {
+			if j > 0 && p.Price == s.Points[j-1].Price {
+				continue
+			}
+			change := ""
+			if j > 0 {
+				change = formatChangePct(p.ChangePct)
+			}
+			fmt.Fprintf(w, "  %-10s %10s %8s\n", p.Time.Format("2006-01-02"), formatMoneyPrice(p.Price), change)
+		}
+	}
+	return nil
+}
+
+// formatMoneyPrice formats a price in the display currency with the precision of formatPrice
+func formatMoneyPrice(v float64) string {
+	return currencySymbol() + formatPrice(v)
+}
+
+func init() {
+	for _, c := range []*cobra.Command{pricesDiffCmd, pricesHistoryCmd} {
+		c.Flags().StringSlice("provider", nil, "Only show these providers (repeatable), e.g. aws")
+		c.Flags().StringSliceP("region", "r", nil, "Only show these regions or geographies (repeatable)")
+		c.Flags().String("pricing-model", "", "Only show one pricing model: "+strings.Join(pricingModels, ", ")+" (default: all)")
+		getPricesCmd.AddCommand(c)
+	}
+	pricesDiffCmd.Flags().String("since", "", "Date to compare the latest prices with, e.g. 2026-01-01")
+}
@@ -0,0 +1,317 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// explorerModels are the pricing models the explorer cycles through with m
+var explorerModels = []string{"on-demand", "commit-1y", "commit-3y", "spot"}
+
+// Styles of the explorer on top of the pricing table styles
+var (
+	explorerCursorStyle = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("212"))
+	explorerPinStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("214"))
+	explorerHelpStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+)
+
+// explorerModel is the Bubble Tea model of prices --interactive
+type explorerModel struct {
+	rows       []priceRow // every row in the current pricing model
+	visible    []priceRow // rows matching the filter, sorted
+	pinned     []priceRow
+	showRegion bool
+
+	cursor, offset int
+	height         int
+
+	filter    string
+	filtering bool
+
+	sortColumn int // index of the provider sorted by, or -1 for catalog order
+	sortDesc   bool
+	models     []string // the pricing models m cycles through
+	model      int      // index in models
+}
+
+// runPricesExplorer opens the interactive pricing table
+func runPricesExplorer() error {
+	if !isTerminal(os.Stdout) {
+		return withExitCode(exitUsage, fmt.Errorf("--interactive needs a terminal, use --output for scripts"))
+	}
+	m := explorerModel{sortColumn: -1, height: 24, models: explorerCycle(pricingModel)}
+	for i, model := range m.models {
+		if model == pricingModel {
+			m.model = i
+		}
+	}
+	m.reload()
+	_, err := tea.NewProgram(m, tea.WithAltScreen()).Run()
+	if err != nil {
+		return fmt.Errorf("starting program: %v", err)
+	}
+	return nil
+}
+
+// explorerCycle returns the pricing models to cycle through when the explorer
+// starts in a model: explorerModels, with the starting model in its place among
+// pricingModels when it isn't one of them
+func explorerCycle(start string) []string {
+	var models []string
+	for _, model := range pricingModels {
+		if model == start || contains(explorerModels, model) {
+			models = append(models, model)
+		}
+	}
+	return models
+}
+
+// reload rebuilds the rows for the selected pricing model
+func (m *explorerModel) reload() {
+	pricingModel = m.models[m.model]
+	m.rows, m.showRegion = nil, false
+	for _, service := range prices.serviceNames() {
+		for _, r := range pricingRows(service) {
+			m.rows = append(m.rows, r)
+			if r.region != "" {
+				m.showRegion = true
+			}
+		}
+	}
+	m.refresh()
+}
+
+// refresh applies the filter and sort order to the rows and keeps the cursor in range
+func (m *explorerModel) refresh() {
+	m.visible = nil
+	for _, r := range m.rows {
+		if fuzzyMatch(m.filter, r.service+" "+r.size+" "+r.region) {
+			m.visible = append(m.visible, r)
+		}
+	}
+	if m.sortColumn >= 0 {
+		provider := prices.Providers[m.sortColumn].Name
+		sort.SliceStable(m.visible, func(i, j int) bool {
+			a, okA := prices.lookup(provider, m.visible[i].service, m.visible[i].size, m.visible[i].region)
+			b, okB := prices.lookup(provider, m.visible[j].service, m.visible[j].size, m.visible[j].region)
+			if okA != okB {
+				return okA // rows the provider doesn't price go last
+			}
+			if m.sortDesc {
+				return a > b
+			}
+			return a < b
+		})
+	}
+	if m.cursor >= len(m.visible) {
+		m.cursor = len(m.visible) - 1
+	}
+	if m.cursor < 0 {
+		m.cursor = 0
+	}
+	m.scroll()
+}
+
+// pageSize is how many rows fit between the header and the footer
+func (m explorerModel) pageSize() int {
+	size := m.height - 12 - len(m.pinned)
+	if len(m.pinned) > 0 {
+		size -= 2
+	}
+	if size < 3 {
+		size = 3
+	}
+	return size
+}
+
+// scroll keeps the cursor row on screen
+func (m *explorerModel) scroll() {
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	}
+	if m.cursor >= m.offset+m.pageSize() {
+		m.offset = m.cursor - m.pageSize() + 1
+	}
+}
+
+// togglePin pins or unpins the row under the cursor
+func (m *explorerModel) togglePin() {
+	if len(m.visible) == 0 {
+		return
+	}
+	r := m.visible[m.cursor]
+	for i, p := range m.pinned {
+		if p == r {
+			m.pinned = append(m.pinned[:i], m.pinned[i+1:]...)
+			return
+		}
+	}
+	m.pinned = append(m.pinned, r)
+}
+
+// isPinned reports whether a row is pinned
+func (m explorerModel) isPinned(r priceRow) bool {
+	for _, p := range m.pinned {
+		if p == r {
+			return true
+		}
+	}
+	return false
+}
+
+func (m explorerModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m explorerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.height = msg.Height
+		m.scroll()
+
+	case tea.KeyMsg:
+		// While typing a filter, keys edit the search box
+		if m.filtering {
+			switch msg.Type {
+			case tea.KeyEnter:
+				m.filtering = false
+			case tea.KeyEsc:
+				m.filtering, m.filter = false, ""
+			case tea.KeyBackspace:
+				if r := []rune(m.filter); len(r) > 0 {
+					m.filter = string(r[:len(r)-1])
+				}
+			case tea.KeyCtrlC:
+				return m, tea.Quit
+			case tea.KeyRunes, tea.KeySpace:
+				m.filter += string(msg.Runes)
+			}
+			m.refresh()
+			return m, nil
+		}
+
+		switch msg.String() {
+		case "q", "esc", "ctrl+c":
+			return m, tea.Quit
+		case "up", "k":
+			m.cursor--
+		case "down", "j":
+			m.cursor++
+		case "pgup":
+			m.cursor -= m.pageSize()
+		case "pgdown":
+			m.cursor += m.pageSize()
+		case "home", "g":
+			m.cursor = 0
+		case "end", "G":
+			m.cursor = len(m.visible) - 1
+		case "/":
+			m.filtering = true
+		case "p", " ":
+			m.togglePin()
+		case "m":
+			m.model = (m.model + 1) % len(m.models)
+			m.reload()
+		case "M":
+			m.model = (m.model + len(m.models) - 1) % len(m.models)
+			m.reload()
+		case "0":
+			m.sortColumn, m.sortDesc = -1, false
+		default:
+			// 1-9 sort by that provider's column; again to reverse the order
+			key := msg.String()
+			if len(key) == 1 && key[0] >= '1' && key[0] <= '9' {
+				column := int(key[0] - '1')
+				if column < len(prices.Providers) {
+					if m.sortColumn == column {
+						m.sortDesc = !m.sortDesc
+					} else {
+						m.sortColumn, m.sortDesc = column, false
+					}
+				}
+			}
+		}
+		m.refresh()
+	}
+	return m, nil
+}
+
+func (m explorerModel) View() string {
+	var b strings.Builder
+	b.WriteString(headerStyle.Render("Cloud prices explorer") + "\n")
+	b.WriteString(lineStyle.Render("Catalog: "+catalogSource+" · pricing model: "+describePricingModel(pricingModel)) + "\n")
+	if note := currencyNote(); note != "" {
+		b.WriteString(lineStyle.Render(note) + "\n")
+	}
+	b.WriteString(legendView() + "\n\n")
+
+	header, width := pricingTableHeader(m.showRegion)
+	if m.sortColumn >= 0 {
+		arrow := "▲"
+		if m.sortDesc {
+			arrow = "▼"
+		}
+		header += "  sorted by " + prices.Providers[m.sortColumn].DisplayName() + " " + arrow
+	}
+	b.WriteString("  " + headerStyle.Render(header) + "\n")
+	b.WriteString("  " + lineStyle.Render(strings.Repeat("-", width)) + "\n")
+
+	if len(m.pinned) > 0 {
+		for _, r := range m.pinned {
+			b.WriteString(explorerPinStyle.Render("* ") + pricingTableRow(r, m.showRegion) + "\n")
+		}
+		b.WriteString("  " + lineStyle.Render(strings.Repeat("-", width)) + "\n")
+	}
+
+	end := m.offset + m.pageSize()
+	if end > len(m.visible) {
+		end = len(m.visible)
+	}
+	for i := m.offset; i < end; i++ {
+		r := m.visible[i]
+		marker := "  "
+		switch {
+		case i == m.cursor:
+			marker = explorerCursorStyle.Render("> ")
+		case m.isPinned(r):
+			marker = explorerPinStyle.Render("* ")
+		}
+		b.WriteString(marker + pricingTableRow(r, m.showRegion) + "\n")
+	}
+	if len(m.visible) == 0 {
+		b.WriteString("  " + infoStyle.Render("No rows match the filter") + "\n")
+	}
+	b.WriteString("  " + lineStyle.Render(strings.Repeat("-", width)) + "\n")
+
+	filter := m.filter
+	if m.filtering {
+		filter += "▏"
+	}
+	b.WriteString(fmt.Sprintf("  Filter: %s  %s\n", filter, explorerHelpStyle.Render(fmt.Sprintf("(%d of %d rows)", len(m.visible), len(m.rows)))))
+	b.WriteString(explorerHelpStyle.Render("  ↑/↓ scroll · / filter · 1-9 sort by provider · 0 catalog order · m pricing model · p pin · q quit"))
+	return b.String()
+}
+
+// fuzzyMatch reports whether every word of the query appears in s as a
+// case-insensitive subsequence, e.g. "cmp lrg use" matches "compute large us-east"
+func fuzzyMatch(query, s string) bool {
+	s = strings.ToLower(s)
+	for _, word := range strings.Fields(strings.ToLower(query)) {
+		w := []rune(word)
+		i := 0
+		for _, r := range s {
+			if i < len(w) && r == w[i] {
+				i++
+			}
+		}
+		if i < len(w) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFuzzyMatch(t *testing.T) {
+	tests := []struct {
+		query, s string
+		want     bool
+	}{
+		{"cmp lrg use", "compute large us-east", true},
+		{"lrg cmp", "compute large us-east", true},
+		{"cmpx", "compute large us-east", false},
+		// Multi-byte runes count once, not by their bytes
+		{"zür", "zürich compute", true},
+		{"züx", "zürich compute", false},
+		{"", "compute", true},
+	}
+	for _, tt := range tests {
+		if got := fuzzyMatch(tt.query, tt.s); got != tt.want {
+			t.Errorf("fuzzyMatch(%q, %q) = %v, want %v", tt.query, tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestExplorerCycle(t *testing.T) {
+	tests := []struct {
+		start string
+		want  []string
+	}{
+		{"on-demand", []string{"on-demand", "commit-1y", "commit-3y", "spot"}},
+		{"spot", []string{"on-demand", "commit-1y", "commit-3y", "spot"}},
+		{"reserved-1y", []string{"on-demand", "commit-1y", "commit-3y", "reserved-1y", "spot"}},
+		{"cud-3y", []string{"on-demand", "commit-1y", "commit-3y", "cud-3y", "spot"}},
+	}
+	for _, tt := range tests {
+		if got := explorerCycle(tt.start); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("explorerCycle(%q) = %v, want %v", tt.start, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+)
+
+// pricingModelFamilies group the equivalent pricing models of different providers,
+// so e.g. AWS 1-year reserved instances are compared with GCP 1-year CUDs
+var pricingModelFamilies = map[string][]string{
+	"commit-1y": {"reserved-1y", "savings-plan-1y", "cud-1y"},
+	"commit-3y": {"reserved-3y", "savings-plan-3y", "cud-3y"},
+}
+
+// pricingModels are the values accepted by --pricing-model
+var pricingModels = []string{"on-demand", "commit-1y", "commit-3y", "reserved-1y", "reserved-3y",
+	"savings-plan-1y", "savings-plan-3y", "cud-1y", "cud-3y", "spot"}
+
+// pricingModel is the pricing model catalog prices are looked up in
+var pricingModel = "on-demand"
+
+// setPricingModel validates and applies --pricing-model
+func setPricingModel(model string) error {
+	model = strings.ToLower(model)
+	if model == "" {
+		model = "on-demand"
+	}
+	if !contains(pricingModels, model) {
+		return fmt.Errorf("unknown pricing model %q, expected one of %s", model, strings.Join(pricingModels, ", "))
+	}
+	pricingModel = model
+	return nil
+}
+
+// modelMatches reports whether a SKU's pricing model is in the selected pricing
+// model, either the same model or one of its family. SKUs without a model are on-demand.
+func modelMatches(skuModel, selected string) bool {
+	if selected == "on-demand" {
+		return skuModel == ""
+	}
+	if family, ok := pricingModelFamilies[selected]; ok {
+		return contains(family, skuModel)
+	}
+	return skuModel == selected
+}
+
+// isCommitment reports whether the selected pricing model bills a fixed term,
+// which is paid for every hour of the month whether it is used or not
+func isCommitment(model string) bool {
+	return model != "on-demand" && model != "spot"
+}
+
+// breakEvenHours returns the hours of use per month from which a commitment priced
+// per hour costs less than on-demand. The boolean is false when the commitment
+// costs more than running on-demand all month.
+func breakEvenHours(onDemand, committed float64) (float64, bool) {
+	if onDemand <= 0 {
+		return 0, false
+	}
+	hours := committed * hoursPerMonth / onDemand
+	return hours, hours <= hoursPerMonth
+}
@@ -0,0 +1,209 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// defaultProfile is the profile used until another one is selected
+const defaultProfile = "default"
+
+// currentProfile is the active profile, selected by loadSettings
+var currentProfile = defaultProfile
+
+// profileNamePattern is what profile names look like, since they name folders
+var profileNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// profileCmd groups the commands that manage profiles
+var profileCmd = &cobra.Command{
+	Use:   "profile",
+	Short: "Manage profiles, each with its own API key, API URL and defaults",
+	Long: `Profiles keep an API key, an API base URL and default settings apart, e.g.
+for a staging and a production account or for teammates sharing a machine.
+
+Create a profile by storing its key with 'cloudcents auth --profile <name>', and
+give it settings with 'cloudcents config set --profile <name>'. Commands use the
+profile from --profile, then CLOUDCENTS_PROFILE, then 'cloudcents profile use'.`,
+}
+
+// profileListCmd lists the profiles
+var profileListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the profiles",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := getOutputFormat(cmd)
+		if err != nil {
+			return err
+		}
+
+		type profileRecord struct {
+			Name   string `json:"name" yaml:"name"`
+			Active bool   `json:"active" yaml:"active"`
+			APIURL string `json:"api_url" yaml:"api_url"`
+			APIKey bool   `json:"api_key" yaml:"api_key"` // whether an API key is stored
+		}
+		var records []profileRecord
+		var rows [][]string
+		active := currentProfile
+		for _, name := range profileNames() {
+			currentProfile = name
+			r := profileRecord{name, name == active, configValue("api_url"), hasAPIKey(name)}
+			records = append(records, r)
+			rows = append(rows, []string{r.Name, fmt.Sprint(r.Active), r.APIURL, fmt.Sprint(r.APIKey)})
+		}
+		currentProfile = active
+
+		header := []string{"name", "active", "api_url", "api_key"}
+		switch format {
+		case "json":
+			return writeJSON(os.Stdout, records)
+		case "yaml":
+			return writeYAML(os.Stdout, records)
+		case "csv":
+			return writeCSV(os.Stdout, header, rows)
+		case "markdown":
+			return writeMarkdownTable(os.Stdout, header, rows)
+		}
+		fmt.Println(headerStyle.Render(fmt.Sprintf("  %-16s %-40s %s", "Profile", "API URL", "API key")))
+		fmt.Println(lineStyle.Render(strings.Repeat("-", 70)))
+		for _, r := range records {
+			marker, key := "  ", "none"
+			if r.Active {
+				marker = successStyle.Render("* ")
+			}
+			if r.APIKey {
+				key = "stored"
+			}
+			fmt.Printf("%s%-16s %-40s %s\n", marker, r.Name, r.APIURL, key)
+		}
+		return nil
+	},
+}
+
+// profileUseCmd makes a profile the active one
+var profileUseCmd = &cobra.Command{
+	Use:   "use <name>",
+	Short: "Use a profile from now on",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+		if !contains(profileNames(), name) {
+			return fmt.Errorf("no profile '%s', create it with 'cloudcents auth --profile %s'", name, name)
+		}
+
+		config, err := readConfigMap()
+		if err != nil {
+			return err
+		}
+		var value interface{} = name
+		if name == defaultProfile {
+			value = nil
+		}
+		setConfigValue(config, []string{"profile"}, value)
+		if err := writeConfigFile(config); err != nil {
+			return fmt.Errorf("writing config file '%s': %v", configPath(), err)
+		}
+		displaySuccess(fmt.Sprintf("Using profile %s", name))
+		if env := os.Getenv(envName("profile")); env != "" && env != name {
+			fmt.Println(infoStyle.Render(fmt.Sprintf("%s is set to %s and takes precedence.", envName("profile"), env)))
+		}
+		return nil
+	},
+}
+
+// profileDeleteCmd deletes a profile with its API key and settings
+var profileDeleteCmd = &cobra.Command{
+	Use:   "delete <name>",
+	Short: "Delete a profile with its API key and settings",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+		if name == defaultProfile {
+			return withExitCode(exitUsage, fmt.Errorf("the default profile can't be deleted"))
+		}
+		if !contains(profileNames(), name) {
+			return fmt.Errorf("no profile '%s'", name)
+		}
+
+		config, err := readConfigMap()
+		if err != nil {
+			return err
+		}
+		setConfigValue(config, []string{"profiles", name}, nil)
+		if config["profile"] == name {
+			setConfigValue(config, []string{"profile"}, nil)
+		}
+		if err := writeConfigFile(config); err != nil {
+			return fmt.Errorf("writing config file '%s': %v", configPath(), err)
+		}
+		if _, err := deleteAPIKey(name); err != nil {
+			return fmt.Errorf("deleting the API key of profile %s: %v", name, err)
+		}
+		if err := os.RemoveAll(filepath.Dir(apiKeyPath(name))); err != nil {
+			return fmt.Errorf("deleting the API key of profile %s: %v", name, err)
+		}
+		displaySuccess(fmt.Sprintf("Deleted profile %s", name))
+		return nil
+	},
+}
+
+// validateProfileName checks that a profile name can name a folder
+func validateProfileName(name string) error {
+	if !profileNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid profile name '%s': use lowercase letters, digits, - and _", name)
+	}
+	return nil
+}
+
+// apiKeyPath returns the path of the stored API key of a profile. The default
+// profile keeps the file from before there were profiles.
+func apiKeyPath(profile string) string {
+	if profile == defaultProfile {
+		return filepath.Join(getConfigDir(), "api_key.txt")
+	}
+	return filepath.Join(getConfigDir(), "profiles", profile, "api_key.txt")
+}
+
+// hasAPIKey reports whether a profile has a stored API key, in any secret store
+func hasAPIKey(profile string) bool {
+	return fileStore{}.has(profile) || keyringAvailable() && keyringStore{}.has(profile)
+}
+
+// profileNames returns the default profile and every profile with settings in the
+// config file or an API key file, sorted
+func profileNames() []string {
+	seen := map[string]bool{defaultProfile: true}
+	for key := range fileSettings {
+		if parts := strings.SplitN(key, ".", 3); len(parts) == 3 && parts[0] == "profiles" {
+			seen[parts[1]] = true
+		}
+	}
+	entries, _ := os.ReadDir(filepath.Join(getConfigDir(), "profiles"))
+	for _, e := range entries {
+		if e.IsDir() && validateProfileName(e.Name()) == nil {
+			seen[e.Name()] = true
+		}
+	}
+
+	var names []string
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	rootCmd.PersistentFlags().String("profile", defaultProfile, "Profile to use (default: $CLOUDCENTS_PROFILE, then the one selected with 'cloudcents profile use')")
+	profileCmd.AddCommand(profileListCmd)
+	profileCmd.AddCommand(profileUseCmd)
+	profileCmd.AddCommand(profileDeleteCmd)
+	rootCmd.AddCommand(profileCmd)
+}
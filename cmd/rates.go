@@ -0,0 +1,322 @@
+package cmd
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// defaultRates are the exchange rates built into the binary, used until rates are imported
+//
+//go:embed data/rates.json
+var defaultRates []byte
+
+// exchangeRates is a table of exchange rates on a date. Rates are units of each
+// currency per unit of the base currency, like the ECB reference rates.
+type exchangeRates struct {
+	Version string             `json:"version"` // the date of the rates, e.g. 2024-10-31
+	Base    string             `json:"base"`
+	Source  string             `json:"source,omitempty"`
+	Rates   map[string]float64 `json:"rates"`
+}
+
+// rate returns how many units of to one unit of from is worth
+func (r exchangeRates) rate(from, to string) (float64, error) {
+	units := func(currency string) (float64, error) {
+		if currency == r.Base {
+			return 1, nil
+		}
+		v, ok := r.Rates[currency]
+		if !ok || v <= 0 {
+			var known []string
+			for c := range r.Rates {
+				known = append(known, c)
+			}
+			known = append(known, r.Base)
+			sort.Strings(known)
+			return 0, fmt.Errorf("no exchange rate for %s, expected one of %s", currency, strings.Join(known, ", "))
+		}
+		return v, nil
+	}
+	f, err := units(from)
+	if err != nil {
+		return 0, err
+	}
+	t, err := units(to)
+	if err != nil {
+		return 0, err
+	}
+	return t / f, nil
+}
+
+// ratesCmd groups the commands that manage exchange rates
+var ratesCmd = &cobra.Command{
+	Use:   "rates",
+	Short: "Manage the exchange rates used by --currency",
+}
+
+// ratesImportCmd imports ECB reference rates into the local rates file
+var ratesImportCmd = &cobra.Command{
+	Use:   "import <eurofxref.csv|eurofxref.xml>",
+	Short: "Import ECB euro reference rates from a CSV or XML file",
+	Long: `Import exchange rates from a European Central Bank reference rates file,
+either the CSV (eurofxref.csv or eurofxref-hist.csv) or the XML
+(eurofxref-daily.xml) format. For files with several days the latest day is
+imported. The rates are written to the local rates file and used by --currency
+from then on. No network access is needed.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		data, err := os.ReadFile(args[0])
+		if err != nil {
+			return fmt.Errorf("reading rates file: %v", err)
+		}
+		rates, err := parseECBRates(data)
+		if err != nil {
+			return fmt.Errorf("parsing ECB rates file '%s': %v", args[0], err)
+		}
+		rates.Source = "ECB euro foreign exchange reference rates, imported from " + filepath.Base(args[0])
+
+		path := localRatesPath()
+		if current, err := readRatesFile(path); err == nil && current.Version > rates.Version {
+			displayError(fmt.Sprintf("The imported rates (%s) are older than the ones they replace (%s).", rates.Version, current.Version))
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		out, err := json.MarshalIndent(rates, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, append(out, '\n'), 0644); err != nil {
+			return fmt.Errorf("writing rates file '%s': %v", path, err)
+		}
+		displaySuccess(fmt.Sprintf("Imported %d exchange rates of %s into '%s'", len(rates.Rates), rates.Version, path))
+		return nil
+	},
+}
+
+// ratesShowCmd prints the exchange rates --currency uses
+var ratesShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Show the exchange rates used by --currency",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := getOutputFormat(cmd)
+		if err != nil {
+			return err
+		}
+		rates, source, err := loadRates()
+		if err != nil {
+			return err
+		}
+
+		// Show rates per US dollar, the currency of the catalog
+		type rateRecord struct {
+			Currency string  `json:"currency" yaml:"currency"`
+			PerUSD   float64 `json:"per_usd" yaml:"per_usd"`
+			Date     string  `json:"date" yaml:"date"`
+		}
+		var records []rateRecord
+		currencies := []string{rates.Base}
+		for c := range rates.Rates {
+			currencies = append(currencies, c)
+		}
+		sort.Strings(currencies)
+		for _, c := range currencies {
+			rate, err := rates.rate(catalogCurrency, c)
+			if err != nil {
+				return err
+			}
+			records = append(records, rateRecord{c, roundPrice(rate), rates.Version})
+		}
+
+		switch format {
+		case "json":
+			return writeJSON(os.Stdout, records)
+		case "yaml":
+			return writeYAML(os.Stdout, records)
+		}
+		var rows [][]string
+		for _, r := range records {
+			rows = append(rows, []string{r.Currency, strconv.FormatFloat(r.PerUSD, 'f', -1, 64), r.Date})
+		}
+		header := []string{"currency", "per_usd", "date"}
+		switch format {
+		case "csv":
+			return writeCSV(os.Stdout, header, rows)
+		case "markdown":
+			return writeMarkdownTable(os.Stdout, header, rows)
+		}
+		fmt.Println(lineStyle.Render(fmt.Sprintf("Rates: %s, %s", source, rates.Version)))
+		fmt.Println(headerStyle.Render(fmt.Sprintf("%-10s %12s", "Currency", "Per USD")))
+		fmt.Println(lineStyle.Render(strings.Repeat("-", 23)))
+		for _, r := range records {
+			fmt.Printf("%-10s %12s\n", r.Currency, strconv.FormatFloat(r.PerUSD, 'f', -1, 64))
+		}
+		return nil
+	},
+}
+
+// localRatesPath returns the path of the rates file that imports are written to
+func localRatesPath() string {
+	return filepath.Join(getConfigDir(), "rates.json")
+}
+
+// loadRates returns the imported exchange rates, or the built-in rates when none
+// were imported, with where they came from
+func loadRates() (exchangeRates, string, error) {
+	path := localRatesPath()
+	if _, err := os.Stat(path); err == nil {
+		rates, err := readRatesFile(path)
+		if err != nil {
+			return rates, path, fmt.Errorf("could not load exchange rates '%s': %v", path, err)
+		}
+		return rates, path, nil
+	}
+	var rates exchangeRates
+	if err := json.Unmarshal(defaultRates, &rates); err != nil {
+		return rates, "", fmt.Errorf("built-in exchange rates are invalid: %v", err)
+	}
+	return rates, "built-in rates", nil
+}
+
+// readRatesFile reads an exchange rates file written by rates import
+func readRatesFile(path string) (exchangeRates, error) {
+	var rates exchangeRates
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return rates, err
+	}
+	if err := json.Unmarshal(data, &rates); err != nil {
+		return rates, err
+	}
+	if rates.Base == "" || len(rates.Rates) == 0 {
+		return rates, fmt.Errorf("no rates in file")
+	}
+	return rates, nil
+}
+
+// ecbEnvelope is the XML format of ECB reference rates
+type ecbEnvelope struct {
+	Days []struct {
+		Time  string `xml:"time,attr"`
+		Rates []struct {
+			Currency string `xml:"currency,attr"`
+			Rate     string `xml:"rate,attr"`
+		} `xml:"Cube"`
+	} `xml:"Cube>Cube"`
+}
+
+// parseECBRates parses the latest day of an ECB reference rates file in CSV or XML.
+// ECB rates are units of each currency per euro.
+func parseECBRates(data []byte) (exchangeRates, error) {
+	rates := exchangeRates{Base: "EUR", Rates: map[string]float64{}}
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("<")) {
+		var env ecbEnvelope
+		if err := xml.Unmarshal(data, &env); err != nil {
+			return rates, err
+		}
+		for _, day := range env.Days {
+			if day.Time <= rates.Version {
+				continue
+			}
+			rates.Version = day.Time
+			rates.Rates = map[string]float64{}
+			for _, r := range day.Rates {
+				v, ok, err := parseECBRate(r.Currency, r.Rate)
+				if err != nil {
+					return rates, fmt.Errorf("%s: %v", day.Time, err)
+				}
+				if ok {
+					rates.Rates[r.Currency] = v
+				}
+			}
+		}
+	} else {
+		r := csv.NewReader(bytes.NewReader(data))
+		r.TrimLeadingSpace = true
+		r.FieldsPerRecord = -1
+		records, err := r.ReadAll()
+		if err != nil {
+			return rates, err
+		}
+		if len(records) < 2 || !strings.EqualFold(records[0][0], "Date") {
+			return rates, fmt.Errorf("expected a header starting with Date and a row of rates")
+		}
+		header := records[0]
+		for n, row := range records[1:] {
+			if len(row) != len(header) {
+				return rates, fmt.Errorf("row %d has %d fields, header has %d", n+2, len(row), len(header))
+			}
+			date, err := parseECBDate(row[0])
+			if err != nil {
+				return rates, err
+			}
+			if date <= rates.Version {
+				continue
+			}
+			rates.Version = date
+			rates.Rates = map[string]float64{}
+			for i, field := range row[1:] {
+				currency := strings.TrimSpace(header[i+1])
+				if currency == "" {
+					continue
+				}
+				v, ok, err := parseECBRate(currency, field)
+				if err != nil {
+					return rates, fmt.Errorf("row %d: %v", n+2, err)
+				}
+				if ok {
+					rates.Rates[currency] = v
+				}
+			}
+		}
+	}
+	if rates.Version == "" || len(rates.Rates) == 0 {
+		return rates, fmt.Errorf("no rates found")
+	}
+	return rates, nil
+}
+
+// parseECBRate parses the rate of a currency. The history files have N/A or
+// nothing for days a currency has no rate, which the boolean is false for.
+func parseECBRate(currency, s string) (float64, bool, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || strings.EqualFold(s, "N/A") {
+		return 0, false, nil
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || v <= 0 {
+		return 0, false, fmt.Errorf("invalid %s rate %q", currency, s)
+	}
+	return v, true, nil
+}
+
+// parseECBDate parses the dates of ECB CSV files: "31 October 2024" in the daily
+// file and "2024-10-31" in the history file
+func parseECBDate(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range []string{"2006-01-02", "2 January 2006"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.Format("2006-01-02"), nil
+		}
+	}
+	return "", fmt.Errorf("invalid date %q", s)
+}
+
+func init() {
+	ratesCmd.AddCommand(ratesImportCmd)
+	ratesCmd.AddCommand(ratesShowCmd)
+	rootCmd.AddCommand(ratesCmd)
+}
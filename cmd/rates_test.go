@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestParseECBRates(t *testing.T) {
+	readFixture := func(name string) string {
+		data, err := os.ReadFile("../testdata/ecb/" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+	tests := []struct {
+		name    string
+		data    string
+		version string
+		rates   map[string]float64 // a few rates to check
+		count   int
+		err     string
+	}{
+		{name: "csv", data: readFixture("eurofxref.csv"), version: "2024-11-15",
+			rates: map[string]float64{"USD": 1.054, "JPY": 163.55, "ZAR": 19.2232}, count: 30},
+		{name: "xml", data: readFixture("eurofxref-daily.xml"), version: "2024-11-15",
+			rates: map[string]float64{"USD": 1.054, "GBP": 0.8356, "SGD": 1.413}, count: 6},
+		{name: "history with missing rates", data: "Date,USD,CYP\n2024-11-14,1.0562,N/A\n2024-11-15,1.0540,\n", version: "2024-11-15",
+			rates: map[string]float64{"USD": 1.054}, count: 1},
+		{name: "ragged row", data: "Date, USD, JPY\n15 November 2024, 1.0540, 163.55, 9.99\n",
+			err: "row 2 has 4 fields, header has 3"},
+		{name: "empty", data: "", err: "expected a header starting with Date and a row of rates"},
+		{name: "non-numeric csv rate", data: "Date, USD\n15 November 2024, 1.05x\n",
+			err: `row 2: invalid USD rate "1.05x"`},
+		{name: "non-numeric xml rate", data: `<Envelope><Cube><Cube time="2024-11-15"><Cube currency="USD" rate="one"/></Cube></Cube></Envelope>`,
+			err: `2024-11-15: invalid USD rate "one"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rates, err := parseECBRates([]byte(tt.data))
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("got error %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseECBRates: %v", err)
+			}
+			if rates.Base != "EUR" || rates.Version != tt.version || len(rates.Rates) != tt.count {
+				t.Errorf("got base %s, version %s and %d rates; want EUR, %s and %d", rates.Base, rates.Version, len(rates.Rates), tt.version, tt.count)
+			}
+			for currency, want := range tt.rates {
+				if got := rates.Rates[currency]; got != want {
+					t.Errorf("got %s rate %v, want %v", currency, got, want)
+				}
+			}
+		})
+	}
+}
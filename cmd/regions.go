@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+)
+
+// regionGeographies maps AWS, GCP and Azure region names to the geography used
+// to compare prices across providers
+var regionGeographies = map[string]string{
+	// AWS
+	"us-east-1":      "us-east",
+	"us-east-2":      "us-east",
+	"us-west-1":      "us-west",
+	"us-west-2":      "us-west",
+	"ca-central-1":   "canada",
+	"sa-east-1":      "south-america",
+	"eu-west-1":      "eu-west",
+	"eu-west-2":      "uk",
+	"eu-west-3":      "eu-west",
+	"eu-central-1":   "eu-central",
+	"eu-north-1":     "eu-north",
+	"eu-south-1":     "eu-south",
+	"ap-southeast-1": "asia-southeast",
+	"ap-southeast-2": "australia",
+	"ap-northeast-1": "asia-northeast",
+	"ap-northeast-2": "asia-northeast",
+	"ap-northeast-3": "asia-northeast",
+	"ap-east-1":      "asia-east",
+	"ap-south-1":     "asia-south",
+	"me-south-1":     "middle-east",
+	"af-south-1":     "africa",
+
+	// GCP
+	"us-east1":                "us-east",
+	"us-east4":                "us-east",
+	"us-central1":             "us-central",
+	"us-west1":                "us-west",
+	"us-west2":                "us-west",
+	"us-west3":                "us-west",
+	"us-west4":                "us-west",
+	"northamerica-northeast1": "canada",
+	"northamerica-northeast2": "canada",
+	"southamerica-east1":      "south-america",
+	"europe-west1":            "eu-west",
+	"europe-west2":            "uk",
+	"europe-west3":            "eu-central",
+	"europe-west4":            "eu-west",
+	"europe-west8":            "eu-south",
+	"europe-north1":           "eu-north",
+	"asia-southeast1":         "asia-southeast",
+	"australia-southeast1":    "australia",
+	"asia-northeast1":         "asia-northeast",
+	"asia-northeast2":         "asia-northeast",
+	"asia-northeast3":         "asia-northeast",
+	"asia-east1":              "asia-east",
+	"asia-east2":              "asia-east",
+	"asia-south1":             "asia-south",
+	"me-west1":                "middle-east",
+	"africa-south1":           "africa",
+
+	// Azure
+	"eastus":             "us-east",
+	"eastus2":            "us-east",
+	"centralus":          "us-central",
+	"northcentralus":     "us-central",
+	"southcentralus":     "us-central",
+	"westus":             "us-west",
+	"westus2":            "us-west",
+	"westus3":            "us-west",
+	"canadacentral":      "canada",
+	"brazilsouth":        "south-america",
+	"westeurope":         "eu-west",
+	"northeurope":        "eu-west",
+	"uksouth":            "uk",
+	"ukwest":             "uk",
+	"germanywestcentral": "eu-central",
+	"swedencentral":      "eu-north",
+	"italynorth":         "eu-south",
+	"southeastasia":      "asia-southeast",
+	"australiaeast":      "australia",
+	"japaneast":          "asia-northeast",
+	"koreacentral":       "asia-northeast",
+	"eastasia":           "asia-east",
+	"centralindia":       "asia-south",
+	"uaenorth":           "middle-east",
+	"southafricanorth":   "africa",
+}
+
+// isGeography reports whether name is one of the geographies in regionGeographies
+func isGeography(name string) bool {
+	for _, geo := range regionGeographies {
+		if geo == name {
+			return true
+		}
+	}
+	return false
+}
+
+// regionGeography returns the geography of a provider region. Regions missing
+// from the mapping table are their own geography, so they still group by name.
+func regionGeography(region string) string {
+	region = strings.ToLower(region)
+	if geo, ok := regionGeographies[region]; ok {
+		return geo
+	}
+	return region
+}
+
+// containsFold reports whether list contains s, ignoring case
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveRegions turns --region values (provider regions or geographies) into
+// a de-duplicated list of geographies. Besides the regions in regionGeographies,
+// it accepts the known regions, those of the loaded catalog, which are their own
+// geography when they aren't mapped.
+func resolveRegions(regions, known []string) ([]string, error) {
+	var geos []string
+	seen := map[string]bool{}
+	for _, r := range regions {
+		r = strings.ToLower(strings.TrimSpace(r))
+		if _, ok := regionGeographies[r]; !ok && !isGeography(r) && !containsFold(known, r) {
+			return nil, fmt.Errorf("unknown region %q", r)
+		}
+		geo := regionGeography(r)
+		if !seen[geo] {
+			seen[geo] = true
+			geos = append(geos, geo)
+		}
+	}
+	return geos, nil
+}
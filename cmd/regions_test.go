@@ -0,0 +1,21 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestResolveRegions(t *testing.T) {
+	known := []string{"us-east-1", "fsn1"}
+	geos, err := resolveRegions([]string{"us-east-1", "eastus", "eu-west", "FSN1"}, known)
+	if err != nil {
+		t.Fatalf("resolveRegions: %v", err)
+	}
+	if want := []string{"us-east", "eu-west", "fsn1"}; !reflect.DeepEqual(geos, want) {
+		t.Errorf("got geographies %v, want %v", geos, want)
+	}
+
+	if _, err := resolveRegions([]string{"nbg1"}, known); err == nil || err.Error() != `unknown region "nbg1"` {
+		t.Errorf("got error %v for a region in neither the mapping nor the catalog", err)
+	}
+}
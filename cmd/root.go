@@ -1,24 +1,132 @@
 package cmd
 
 import (
+	"errors"
+	"fmt"
 	"os"
+	"strings"
 
+	"github.com/mattn/go-isatty"
 	"github.com/spf13/cobra"
 )
 
+// Exit codes of cloudcents. Scripts and CI pipelines can rely on them, so only add new ones.
+const (
+	exitOK             = 0 // the command succeeded
+	exitFailure        = 1 // the command failed, e.g. an unreadable file or an invalid catalog
+	exitUsage          = 2 // invalid arguments, flags or command
+	exitBudgetExceeded = 3 // ci check found a cost increase over the threshold
+	exitAuth           = 4 // no stored API key, or the API rejected it as invalid
+	exitKeyExpired     = 5 // the API key or the device login has expired
+	exitKeyRevoked     = 6 // the API key has been revoked
+)
+
 var rootCmd = &cobra.Command{
 	Use:   "cloudcents",
 	Short: "Cloud command-line tool for price fetching and demo",
-	Long:  `cloudcents is a CLI tool to fetch prices and show video demos using stylish terminal output.`,
+	Long: `cloudcents is a CLI tool to fetch prices and show video demos using stylish terminal output.
+
+Exit codes:
+  0  success
+  1  the command failed
+  2  invalid arguments, flags or command
+  3  ci check found a cost increase over the threshold
+  4  not logged in, or the API key is invalid
+  5  the API key or the device login has expired
+  6  the API key has been revoked`,
+	SilenceErrors: true,
+	SilenceUsage:  true,
+	// Environment variables and the config file set the defaults of every command's
+	// flags, and API keys stored by older versions are moved to the secret store
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := applyConfig(cmd); err != nil {
+			return err
+		}
+		migrateLegacyKeys()
+		return nil
+	},
+}
+
+// exitCodeError is an error that makes cloudcents exit with a specific code. Without
+// an underlying error nothing is printed, for commands that already reported why.
+type exitCodeError struct {
+	code int
+	err  error
+}
+
+func (e *exitCodeError) Error() string {
+	if e.err == nil {
+		return fmt.Sprintf("exit status %d", e.code)
+	}
+	return e.err.Error()
+}
+
+func (e *exitCodeError) Unwrap() error {
+	return e.err
+}
+
+// withExitCode returns err with the exit code cloudcents should exit with
+func withExitCode(code int, err error) error {
+	return &exitCodeError{code: code, err: err}
+}
+
+// isTerminal reports whether f is a terminal, including the Cygwin and MSYS2
+// terminals of Windows
+func isTerminal(f *os.File) bool {
+	return isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd())
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
+// Errors are printed to stderr and mapped to the exit codes above.
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
-		os.Exit(1)
+	wrapArgErrors(rootCmd)
+	cmd, err := rootCmd.ExecuteC()
+	if err == nil {
+		os.Exit(exitOK)
+	}
+
+	code := exitFailure
+	var coded *exitCodeError
+	switch {
+	case errors.As(err, &coded):
+		code = coded.code
+		if coded.err == nil {
+			os.Exit(code)
+		}
+	case strings.HasPrefix(err.Error(), "unknown command"):
+		// Cobra reports unknown commands before any argument validation runs
+		code = exitUsage
+	}
+
+	displayError(fmt.Sprintf("Error: %v", err))
+	if code == exitUsage {
+		fmt.Fprintln(os.Stderr, infoStyle.Render(fmt.Sprintf("Run '%s --help' for usage.", cmd.CommandPath())))
+	}
+	os.Exit(code)
+}
+
+// wrapArgErrors marks errors from the argument validators of every command as usage errors
+func wrapArgErrors(cmd *cobra.Command) {
+	if validate := cmd.Args; validate != nil {
+		cmd.Args = func(cmd *cobra.Command, args []string) error {
+			if err := validate(cmd, args); err != nil {
+				return withExitCode(exitUsage, err)
+			}
+			return nil
+		}
+	}
+	for _, c := range cmd.Commands() {
+		wrapArgErrors(c)
 	}
 }
 
 func init() {
-	// Any flags or configuration settings can be added here
+	// Flags win over CLOUDCENTS_* variables, which win over the active profile, the
+	// rest of config.yaml and the defaults, in that order (see loadSettings)
+	rootCmd.PersistentFlags().String("output", "table", "Output format: table, json, csv, markdown or yaml")
+	rootCmd.PersistentFlags().String("currency", catalogCurrency, "Currency to show prices and estimates in, e.g. EUR or INR (see 'cloudcents rates show')")
+	rootCmd.PersistentFlags().String("catalog", "", "Pricing catalog file (default: the built-in catalog with the imported local catalog layered over it)")
+	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return withExitCode(exitUsage, err)
+	})
 }
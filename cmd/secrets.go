@@ -0,0 +1,377 @@
+package cmd
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/x/term"
+	"github.com/zalando/go-keyring"
+	"golang.org/x/crypto/scrypt"
+)
+
+// keyringService is the service API keys are stored under in the OS keyring
+const keyringService = "cloudcents"
+
+// secretStores are the backends of the secret_store setting; auto uses the keyring
+// when the OS has one and the passphrase-encrypted file otherwise
+var secretStores = []string{"auto", "keyring", "file"}
+
+// errNoAPIKey is returned when a profile has no stored API key
+var errNoAPIKey = errors.New("no API key stored, run 'cloudcents auth'")
+
+// secretStore keeps the API key of each profile
+type secretStore interface {
+	name() string
+	get(profile string) (string, error) // errNoAPIKey when there is none
+	set(profile, apiKey string) error
+	delete(profile string) error
+	has(profile string) bool // whether a key is stored, without asking for a passphrase
+}
+
+// keyringStore keeps API keys in the OS keyring: the Secret Service on Linux, the
+// Keychain on macOS and the Credential Manager on Windows
+type keyringStore struct{}
+
+func (keyringStore) name() string {
+	return "OS keyring"
+}
+
+func (keyringStore) get(profile string) (string, error) {
+	apiKey, err := keyring.Get(keyringService, profile)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return "", errNoAPIKey
+	}
+	return apiKey, err
+}
+
+func (keyringStore) set(profile, apiKey string) error {
+	return keyring.Set(keyringService, profile, apiKey)
+}
+
+func (keyringStore) delete(profile string) error {
+	if err := keyring.Delete(keyringService, profile); err != nil && !errors.Is(err, keyring.ErrNotFound) {
+		return err
+	}
+	return nil
+}
+
+func (s keyringStore) has(profile string) bool {
+	_, err := s.get(profile)
+	return err == nil
+}
+
+// keyringAvailable reports whether the OS keyring can be used, e.g. not on a
+// server without a Secret Service
+func keyringAvailable() bool {
+	_, err := keyring.Get(keyringService, "cloudcents-probe")
+	return err == nil || errors.Is(err, keyring.ErrNotFound)
+}
+
+// fileStore keeps API keys in files encrypted with a key derived from a passphrase
+// with scrypt. The passphrase comes from CLOUDCENTS_PASSPHRASE or is asked for.
+type fileStore struct{}
+
+// encryptedKeyFile is the format of the API key files of fileStore
+type encryptedKeyFile struct {
+	Version int    `json:"version"`
+	KDF     string `json:"kdf"`
+	N       int    `json:"n"`
+	R       int    `json:"r"`
+	P       int    `json:"p"`
+	Salt    string `json:"salt"`
+	Data    string `json:"data"` // nonce and AES-256-GCM ciphertext
+}
+
+// scrypt parameters of new key files, the recommended interactive ones
+const (
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+func (fileStore) name() string {
+	return "passphrase-encrypted file"
+}
+
+func (fileStore) get(profile string) (string, error) {
+	data, err := os.ReadFile(apiKeyPath(profile))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", errNoAPIKey
+	}
+	if err != nil {
+		return "", fmt.Errorf("could not read API key: %v", err)
+	}
+	var f encryptedKeyFile
+	if err := json.Unmarshal(data, &f); err != nil || f.Version != 2 || f.KDF != "scrypt" {
+		return "", fmt.Errorf("'%s' is not an encrypted API key file", apiKeyPath(profile))
+	}
+	salt, err1 := base64.StdEncoding.DecodeString(f.Salt)
+	sealed, err2 := base64.StdEncoding.DecodeString(f.Data)
+	if err1 != nil || err2 != nil {
+		return "", fmt.Errorf("'%s' is corrupt", apiKeyPath(profile))
+	}
+
+	passphrase, err := readPassphrase(fmt.Sprintf("Passphrase of the API key of profile %s: ", profile), false)
+	if err != nil {
+		return "", err
+	}
+	key, err := scrypt.Key([]byte(passphrase), salt, f.N, f.R, f.P, 32)
+	if err != nil {
+		return "", err
+	}
+	apiKey, err := openAESGCM(key, sealed)
+	if err != nil {
+		return "", fmt.Errorf("wrong passphrase for the API key of profile %s", profile)
+	}
+	return apiKey, nil
+}
+
+func (fileStore) set(profile, apiKey string) error {
+	passphrase, err := readPassphrase(fmt.Sprintf("New passphrase for the API key of profile %s: ", profile), true)
+	if err != nil {
+		return err
+	}
+	salt := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return err
+	}
+	key, err := scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, 32)
+	if err != nil {
+		return err
+	}
+	sealed, err := sealAESGCM(key, apiKey)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(encryptedKeyFile{
+		Version: 2, KDF: "scrypt", N: scryptN, R: scryptR, P: scryptP,
+		Salt: base64.StdEncoding.EncodeToString(salt),
+		Data: base64.StdEncoding.EncodeToString(sealed),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := apiKeyPath(profile)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("creating config folder: %v", err)
+	}
+	return os.WriteFile(path, append(data, '\n'), 0600)
+}
+
+// delete overwrites the key file before removing it, so the encrypted key doesn't
+// linger on disk
+func (fileStore) delete(profile string) error {
+	path := apiKeyPath(profile)
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	noise := make([]byte, info.Size())
+	if _, err := io.ReadFull(rand.Reader, noise); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(noise)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+func (fileStore) has(profile string) bool {
+	_, err := os.Stat(apiKeyPath(profile))
+	return err == nil
+}
+
+// credentialSource describes where the API key of a profile comes from, or returns
+// "" when it has none. It doesn't ask for a passphrase.
+func credentialSource(profile string) string {
+	switch {
+	case os.Getenv("CLOUDCENTS_API_KEY") != "":
+		return "environment variable CLOUDCENTS_API_KEY"
+	case isLegacyKeyFile(profile):
+		return fmt.Sprintf("legacy key file '%s', moved to the secret store on next use", apiKeyPath(profile))
+	case keyringAvailable() && keyringStore{}.has(profile):
+		return keyringStore{}.name()
+	case fileStore{}.has(profile):
+		return fmt.Sprintf("%s '%s'", fileStore{}.name(), apiKeyPath(profile))
+	}
+	return ""
+}
+
+// enteredPassphrase is the passphrase entered on the terminal, so that storing
+// refreshed login tokens doesn't ask for it again
+var enteredPassphrase string
+
+// readPassphrase returns CLOUDCENTS_PASSPHRASE, or asks for the passphrase on the
+// terminal without echoing it, twice when it's a new one
+func readPassphrase(prompt string, confirm bool) (string, error) {
+	if passphrase := os.Getenv("CLOUDCENTS_PASSPHRASE"); passphrase != "" {
+		return passphrase, nil
+	}
+	if enteredPassphrase != "" {
+		return enteredPassphrase, nil
+	}
+	if !isTerminal(os.Stdin) {
+		return "", fmt.Errorf("the API key is encrypted with a passphrase: set CLOUDCENTS_PASSPHRASE, or CLOUDCENTS_API_KEY in CI")
+	}
+
+	read := func(prompt string) (string, error) {
+		fmt.Fprint(os.Stderr, prompt)
+		passphrase, err := term.ReadPassword(os.Stdin.Fd())
+		fmt.Fprintln(os.Stderr)
+		return string(passphrase), err
+	}
+	passphrase, err := read(prompt)
+	if err != nil {
+		return "", err
+	}
+	if passphrase == "" {
+		return "", fmt.Errorf("the passphrase can't be empty")
+	}
+	if confirm {
+		again, err := read("Repeat the passphrase: ")
+		if err != nil {
+			return "", err
+		}
+		if again != passphrase {
+			return "", fmt.Errorf("the passphrases don't match")
+		}
+	}
+	enteredPassphrase = passphrase
+	return passphrase, nil
+}
+
+// sealAESGCM encrypts plaintext with AES-GCM, returning the nonce and the ciphertext
+func sealAESGCM(key []byte, plaintext string) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, []byte(plaintext), nil), nil
+}
+
+// openAESGCM decrypts the output of sealAESGCM
+func openAESGCM(key, sealed []byte) (string, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", fmt.Errorf("ciphertext too short")
+	}
+	plaintext, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// secrets returns the secret store selected by the secret_store setting
+func secrets() (secretStore, error) {
+	switch strings.ToLower(configValue("secret_store")) {
+	case "keyring":
+		if !keyringAvailable() {
+			return nil, fmt.Errorf("secret_store is keyring, but there is no OS keyring to use")
+		}
+		return keyringStore{}, nil
+	case "file":
+		return fileStore{}, nil
+	}
+	if keyringAvailable() {
+		return keyringStore{}, nil
+	}
+	return fileStore{}, nil
+}
+
+// legacyEncryptionKey is the key API key files were encrypted with before the
+// secret stores. It is built into every binary, so it only serves to migrate them.
+var legacyEncryptionKey = []byte("myverystrongpasswordo32bitlength")
+
+// isLegacyKeyFile reports whether a profile's API key file is in the legacy format
+func isLegacyKeyFile(profile string) bool {
+	data, err := os.ReadFile(apiKeyPath(profile))
+	return err == nil && !strings.HasPrefix(strings.TrimSpace(string(data)), "{")
+}
+
+// migrateLegacyKey moves the API key of a profile from a legacy file into the
+// secret store. Unless interactive, it is left for later when the store would have
+// to ask for a passphrase.
+func migrateLegacyKey(profile string, interactive bool) error {
+	if !isLegacyKeyFile(profile) {
+		return nil
+	}
+	store, err := secrets()
+	if err != nil {
+		return err
+	}
+	if _, ok := store.(fileStore); ok && !interactive && os.Getenv("CLOUDCENTS_PASSPHRASE") == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(apiKeyPath(profile))
+	if err != nil {
+		return err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return fmt.Errorf("'%s' is corrupt: %v", apiKeyPath(profile), err)
+	}
+	apiKey, err := openAESGCM(legacyEncryptionKey, sealed)
+	if err != nil {
+		return fmt.Errorf("'%s' is corrupt: %v", apiKeyPath(profile), err)
+	}
+
+	fmt.Fprintln(os.Stderr, infoStyle.Render(fmt.Sprintf("Moving the API key of profile %s to the %s.", profile, store.name())))
+	if err := store.set(profile, apiKey); err != nil {
+		return fmt.Errorf("moving the API key of profile %s to the %s: %v", profile, store.name(), err)
+	}
+	if _, ok := store.(fileStore); !ok {
+		return fileStore{}.delete(profile)
+	}
+	return nil
+}
+
+// migrateLegacyKeys moves the API keys of every profile from legacy files into the
+// secret store, as far as that's possible without asking for a passphrase
+func migrateLegacyKeys() {
+	for _, profile := range profileNames() {
+		if err := migrateLegacyKey(profile, false); err != nil {
+			displayError(fmt.Sprintf("Could not migrate the API key of profile %s: %v", profile, err))
+		}
+	}
+}
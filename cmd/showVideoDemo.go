@@ -14,11 +14,12 @@ import (
 var showVideoDemoCmd = &cobra.Command{
 	Use:   "demo",
 	Short: "Display a video demo",
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		p := tea.NewProgram(videoModel{})
 		if err := p.Start(); err != nil {
-			fmt.Printf("Error starting program: %v\n", err)
+			return fmt.Errorf("starting program: %v", err)
 		}
+		return nil
 	},
 }
 
@@ -84,21 +85,21 @@ func init() {
 // Open the video link in the browser when the "open" argument is provided
 func init() {
 	showVideoDemoCmd.Flags().BoolP("open", "o", false, "Open video in browser")
-	showVideoDemoCmd.Run = func(cmd *cobra.Command, args []string) {
+	showVideoDemoCmd.RunE = func(cmd *cobra.Command, args []string) error {
 		openVideo, _ := cmd.Flags().GetBool("open")
 		if openVideo {
-			openBrowser("https://www.youtube.com/watch?v=Sx7q05HhqBA")
-		} else {
-			p := tea.NewProgram(videoModel{})
-			if err := p.Start(); err != nil {
-				fmt.Printf("Error starting program: %v\n", err)
-			}
+			return openBrowser("https://www.youtube.com/watch?v=Sx7q05HhqBA")
 		}
+		p := tea.NewProgram(videoModel{})
+		if err := p.Start(); err != nil {
+			return fmt.Errorf("starting program: %v", err)
+		}
+		return nil
 	}
 }
 
 // Open a URL in the default browser
-func openBrowser(url string) {
+func openBrowser(url string) error {
 	var err error
 
 	switch runtime.GOOS {
@@ -109,19 +110,21 @@ func openBrowser(url string) {
 	case "darwin":
 		err = exec.Command("open", url).Start()
 	default:
-		fmt.Printf("Unsupported platform")
+		return fmt.Errorf("opening a browser is not supported on %s", runtime.GOOS)
 	}
 	if err != nil {
-		fmt.Printf("Failed to open browser: %v\n", err)
+		return fmt.Errorf("opening browser: %v", err)
 	}
+	return nil
 }
 
 // escCmd represents the command to exit the CLI
 var escCmd = &cobra.Command{
 	Use:   "esc",
 	Short: "Exit the program",
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		fmt.Println("Exiting the program. Have a great day!")
+		return nil
 	},
 }
 
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Periods accepted by --period; an empty period shows prices in their catalog units
+var pricePeriods = []string{"hour", "month", "year"}
+
+// defaultHoursPerMonth is the average number of hours in a month (8760 / 12)
+const defaultHoursPerMonth = 730
+
+// pricePeriod and hoursPerMonth control how catalog prices are normalized for display
+var (
+	pricePeriod   string
+	hoursPerMonth float64 = defaultHoursPerMonth
+)
+
+// unitLabels are the display names of the catalog units
+var unitLabels = map[string]string{
+	"hour":     "hour",
+	"month":    "month",
+	"gb-month": "GB-month",
+	"gb":       "GB",
+	"request":  "request",
+}
+
+// setPricePeriod validates and applies --period and --hours-per-month
+func setPricePeriod(period string, hours float64) error {
+	period = strings.ToLower(period)
+	if period != "" && !contains(pricePeriods, period) {
+		return fmt.Errorf("unknown period %q, expected one of %s", period, strings.Join(pricePeriods, ", "))
+	}
+	if err := checkHoursPerMonth(hours); err != nil {
+		return err
+	}
+	pricePeriod, hoursPerMonth = period, hours
+	return nil
+}
+
+// checkHoursPerMonth rejects hours per month that no month has
+func checkHoursPerMonth(hours float64) error {
+	if hours <= 0 || hours > 744 {
+		return fmt.Errorf("hours per month must be between 0 and 744, found %g", hours)
+	}
+	return nil
+}
+
+// perMonth returns how many times a unit is charged per month when it is time-based.
+// The boolean is false for usage-based units such as GB transferred or requests.
+func perMonth(unit string) (float64, string, bool) {
+	switch unit {
+	case "hour":
+		return hoursPerMonth, "", true
+	case "month":
+		return 1, "", true
+	case "gb-month":
+		return 1, "GB-", true
+	}
+	return 0, "", false
+}
+
+// normalizePrice converts a price in a catalog unit to the selected period and the
+// display currency. Usage-based prices and prices without a unit keep their unit.
+func normalizePrice(price float64, unit string) float64 {
+	price *= currencyRate
+	times, _, ok := perMonth(unit)
+	if pricePeriod == "" || !ok {
+		return price
+	}
+	monthly := price * times
+	switch pricePeriod {
+	case "hour":
+		return monthly / hoursPerMonth
+	case "year":
+		return monthly * 12
+	}
+	return monthly
+}
+
+// normalizedUnit returns the display unit of a catalog unit in the selected period,
+// e.g. "GB-month" for storage or "month" for compute priced per hour
+func normalizedUnit(unit string) string {
+	_, prefix, ok := perMonth(unit)
+	if pricePeriod == "" || !ok {
+		return unitLabels[unit]
+	}
+	return prefix + pricePeriod
+}
+
+// formatPrice formats a price with enough decimals to tell small prices apart
+func formatPrice(price float64) string {
+	if price != 0 && price < 0.001 {
+		return fmt.Sprintf("%.6f", price)
+	}
+	return fmt.Sprintf("%.3f", price)
+}
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// identity is who the CLI acts as, for whoami
+type identity struct {
+	Profile     string `json:"profile" yaml:"profile"`
+	Account     string `json:"account" yaml:"account"`
+	Org         string `json:"org" yaml:"org"`
+	Plan        string `json:"plan" yaml:"plan"`
+	Key         string `json:"key" yaml:"key"` // masked
+	Fingerprint string `json:"fingerprint" yaml:"fingerprint"`
+}
+
+// whoamiCmd shows the account of the API key in use
+var whoamiCmd = &cobra.Command{
+	Use:   "whoami",
+	Short: "Show the account and organization of the API key in use",
+	Long: `Show the account and organization the API key of the active profile, or
+CLOUDCENTS_API_KEY, belongs to, with the key masked and its fingerprint. The
+fingerprint tells keys apart without revealing them.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := getOutputFormat(cmd)
+		if err != nil {
+			return err
+		}
+		apiKey, err := readAPIKey()
+		if err != nil {
+			return withExitCode(authExitCode(err), err)
+		}
+		a, err := verifyAPIKey(apiKey)
+		if err != nil {
+			if hint := authHint(err); hint != "" {
+				err = fmt.Errorf("%w. %s", err, hint)
+			}
+			return withExitCode(authExitCode(err), err)
+		}
+
+		id := identity{currentProfile, a.Account, a.Org, a.Plan, maskAPIKey(apiKey), keyFingerprint(apiKey)}
+		header := []string{"profile", "account", "org", "plan", "key", "fingerprint"}
+		rows := [][]string{{id.Profile, id.Account, id.Org, id.Plan, id.Key, id.Fingerprint}}
+		switch format {
+		case "json":
+			return writeJSON(os.Stdout, id)
+		case "yaml":
+			return writeYAML(os.Stdout, id)
+		case "csv":
+			return writeCSV(os.Stdout, header, rows)
+		case "markdown":
+			return writeMarkdownTable(os.Stdout, header, rows)
+		}
+		org := id.Org
+		if org == "" {
+			org = "none"
+		}
+		lines := []string{
+			fmt.Sprintf("Account:      %s", id.Account),
+			fmt.Sprintf("Organization: %s", org),
+			fmt.Sprintf("Plan:         %s", id.Plan),
+			fmt.Sprintf("Profile:      %s", id.Profile),
+			fmt.Sprintf("API key:      %s (%s)", id.Key, id.Fingerprint),
+		}
+		fmt.Println(strings.Join(lines, "\n"))
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(whoamiCmd)
+}